@@ -12,6 +12,10 @@ type RespResult struct {
 	ErrCode int    `json:"err_code"`
 	ErrMsg  string `json:"message"`
 	Data    any    `json:"data,omitempty"`
+	Status  int    `json:"-"` // http 状态码, 为 0 时使用 200
+
+	Key  string         `json:"-"` // 消息 key, 写入响应前按请求语言翻译
+	Args map[string]any `json:"-"` // 消息占位符参数
 }
 
 type M[K KeyAble, V any] map[K]V
@@ -0,0 +1,27 @@
+package comm
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type clockFunc func() time.Time
+
+var clock atomic.Value
+
+func init() {
+	clock.Store(clockFunc(time.Now))
+}
+
+// Now 当前时间, token 有效期/会话/限流等业务时间均由此获取, 测试中可通过 SetClock 替换
+func Now() time.Time {
+	return clock.Load().(clockFunc)()
+}
+
+// SetClock 替换时间来源, 传 nil 恢复为系统时间
+func SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	clock.Store(clockFunc(now))
+}
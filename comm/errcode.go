@@ -0,0 +1,14 @@
+package comm
+
+const (
+	ErrCodeSuccess       = 0
+	ErrCodeFail          = -1  // 通用错误
+	ErrCodeBadRequest    = 400 // 请求参数错误
+	ErrCodeUnauthorized  = 401 // 未登录或登录已失效
+	ErrCodeForbidden     = 403 // 无访问权限
+	ErrCodeNotFound      = 404 // 资源不存在
+	ErrCodeNotAcceptable = 406 // 无法按 Accept 返回响应
+	ErrCodeConflict      = 409 // 请求冲突, 例如重复请求正在处理中
+	ErrCodeTooManyReqs   = 429 // 请求过于频繁
+	ErrCodeTimeout       = 504 // 处理超时
+)
@@ -0,0 +1,138 @@
+package comm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Error 业务错误, Code 在注册表中唯一, Key 用于多语言消息
+type Error struct {
+	Code    int
+	Key     string
+	Message string         // 默认消息, 未找到 Key 对应的翻译时使用, 支持 {name} 占位符
+	Args    map[string]any // 占位符参数
+	Status  int            // 响应的 http 状态码
+	cause   error
+}
+
+var (
+	errorMu  sync.RWMutex
+	registry = make(map[int]*Error)
+)
+
+var (
+	ErrFail          = NewError(ErrCodeFail, "error.fail", "系统繁忙,请稍后再试", http.StatusInternalServerError)
+	ErrBadRequest    = NewError(ErrCodeBadRequest, "error.bad_request", "请求参数错误", http.StatusBadRequest)
+	ErrUnauthorized  = NewError(ErrCodeUnauthorized, "error.unauthorized", "请先登录", http.StatusUnauthorized)
+	ErrForbidden     = NewError(ErrCodeForbidden, "error.forbidden", "access denied", http.StatusForbidden)
+	ErrNotFound      = NewError(ErrCodeNotFound, "error.not_found", "not found", http.StatusNotFound)
+	ErrNotAcceptable = NewError(ErrCodeNotAcceptable, "error.not_acceptable", "not acceptable", http.StatusNotAcceptable)
+	ErrConflict      = NewError(ErrCodeConflict, "error.conflict", "请求正在处理中,请勿重复提交", http.StatusConflict)
+	ErrTooManyReqs   = NewError(ErrCodeTooManyReqs, "error.too_many_requests", "请求过于频繁,请稍后再试", http.StatusTooManyRequests)
+	ErrTimeout       = NewError(ErrCodeTimeout, "error.timeout", "timeout", http.StatusGatewayTimeout)
+)
+
+// NewError 创建并注册错误, code 重复时 panic, status 为 0 时使用 200
+func NewError(code int, key, message string, status int) *Error {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	e := &Error{Code: code, Key: key, Message: message, Status: status}
+	RegisterError(e)
+	return e
+}
+
+// RegisterError 注册错误, code 重复时 panic
+func RegisterError(e *Error) {
+	errorMu.Lock()
+	defer errorMu.Unlock()
+	if exists, ok := registry[e.Code]; ok {
+		panic(fmt.Sprintf("error code already registered: %d (%s)", e.Code, exists.Key))
+	}
+	registry[e.Code] = e
+}
+
+// LookupError 按 code 查找已注册的错误
+func LookupError(code int) (*Error, bool) {
+	errorMu.RLock()
+	defer errorMu.RUnlock()
+	e, ok := registry[code]
+	return e, ok
+}
+
+// RegisteredErrors 已注册的错误, 按 code 排序
+func RegisteredErrors() []*Error {
+	errorMu.RLock()
+	defer errorMu.RUnlock()
+	list := make([]*Error, 0, len(registry))
+	for _, e := range registry {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("[%d] %s: %s", e.Code, e.Message, e.cause.Error())
+	}
+	return fmt.Sprintf("[%d] %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is code 相同即认为是同一错误, 可用 errors.Is(err, comm.ErrNotFound) 判断
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if !errors.As(target, &t) {
+		return false
+	}
+	return t.Code == e.Code
+}
+
+// Cause 包装的原始错误
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// Wrap 返回包装 cause 的副本, cause 仅记录日志, 不返回给客户端
+func (e *Error) Wrap(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+// WithMessage 返回使用指定消息的副本, 该消息不再翻译
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Key = ""
+	copied.Message = message
+	return &copied
+}
+
+// WithKey 返回使用其他消息 key 的副本, code 与状态码不变
+func (e *Error) WithKey(key, message string) *Error {
+	copied := *e
+	copied.Key = key
+	copied.Message = message
+	return &copied
+}
+
+// WithArgs 返回设置占位符参数的副本
+func (e *Error) WithArgs(args map[string]any) *Error {
+	copied := *e
+	copied.Args = args
+	return &copied
+}
+
+// Result 转换为响应结果
+func (e *Error) Result() *RespResult {
+	return &RespResult{ErrCode: e.Code, ErrMsg: e.Message, Status: e.Status, Key: e.Key, Args: e.Args}
+}
@@ -0,0 +1,335 @@
+package auth
+
+import (
+	"catuan/comm"
+	"catuan/util"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+var (
+	ErrTokenInvalid  = errors.New("token 无效")
+	ErrTokenExpired  = errors.New("token 已过期")
+	ErrKeyNotFound   = errors.New("token 密钥不存在")
+	ErrAlgNotSupport = errors.New("token 签名算法不支持")
+)
+
+// Claims token 载荷
+type Claims struct {
+	Subject   string         `json:"sub,omitempty"`  // 用户标识
+	Role      string         `json:"role,omitempty"` // 角色标签, 与 web.Role 对应
+	Issuer    string         `json:"iss,omitempty"`
+	Audience  string         `json:"aud,omitempty"`
+	ExpiresAt int64          `json:"exp,omitempty"`
+	NotBefore int64          `json:"nbf,omitempty"`
+	IssuedAt  int64          `json:"iat,omitempty"`
+	Id        string         `json:"jti,omitempty"`
+	Extra     map[string]any `json:"ext,omitempty"`
+}
+
+// Key 签名密钥, HS256 使用 Secret, RS256 使用 PrivateKey 签名 PublicKey 验签
+type Key struct {
+	Kid        string
+	Alg        string
+	Secret     []byte
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// JWT token 签发与验证, 支持多个密钥轮换: 新 token 使用当前密钥签名, 旧密钥保留用于验证
+type JWT struct {
+	mu        sync.RWMutex
+	keys      map[string]*Key
+	activeKid string
+	issuer    string
+	audience  string // 不为空时签发时填充并在验证时校验
+	expires   time.Duration
+	leeway    time.Duration // 允许的时间误差
+}
+
+func NewJWT(issuer string, expires time.Duration) *JWT {
+	return &JWT{
+		keys:    make(map[string]*Key),
+		issuer:  issuer,
+		expires: expires,
+		leeway:  time.Second * 30,
+	}
+}
+
+// SetAudience 设置接收方, 验证时 aud 不一致的 token 视为无效
+func (j *JWT) SetAudience(audience string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.audience = audience
+}
+
+// NewHSKey HS256 密钥
+func NewHSKey(kid string, secret string) *Key {
+	return &Key{Kid: kid, Alg: AlgHS256, Secret: []byte(secret)}
+}
+
+// LoadRSKey 从 PEM 文件加载 RS256 密钥, 仅用于验证时 privateKeyFile 可为空
+func LoadRSKey(kid string, privateKeyFile string, publicKeyFile string) (*Key, error) {
+	key := &Key{Kid: kid, Alg: AlgRS256}
+	if privateKeyFile != "" {
+		block, err := readPem(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			pkcs8Key, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err8 != nil {
+				return nil, err
+			}
+			rsaKey, ok := pkcs8Key.(*rsa.PrivateKey)
+			if !ok {
+				return nil, errors.New("私钥不是 RSA 密钥: " + privateKeyFile)
+			}
+			privateKey = rsaKey
+		}
+		key.PrivateKey = privateKey
+		key.PublicKey = &privateKey.PublicKey
+	}
+	if publicKeyFile != "" {
+		block, err := readPem(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("公钥不是 RSA 密钥: " + publicKeyFile)
+		}
+		key.PublicKey = rsaKey
+	}
+	if key.PublicKey == nil {
+		return nil, errors.New("RS256 密钥缺少公钥: " + kid)
+	}
+	return key, nil
+}
+
+func readPem(file string) (*pem.Block, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("PEM 格式错误: " + file)
+	}
+	return block, nil
+}
+
+// AddKey 添加密钥, active 为 true 时作为签发密钥
+func (j *JWT) AddKey(key *Key, active bool) error {
+	switch key.Alg {
+	case AlgHS256:
+		if len(key.Secret) == 0 {
+			return errors.New("HS256 密钥为空: " + key.Kid)
+		}
+	case AlgRS256:
+		if key.PublicKey == nil {
+			return errors.New("RS256 密钥缺少公钥: " + key.Kid)
+		}
+		if active && key.PrivateKey == nil {
+			return errors.New("签发密钥缺少私钥: " + key.Kid)
+		}
+	default:
+		return ErrAlgNotSupport
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.keys[key.Kid] = key
+	if active {
+		j.activeKid = key.Kid
+	}
+	return nil
+}
+
+// RemoveKey 移除密钥, 使用该密钥签发的 token 将无法通过验证
+func (j *JWT) RemoveKey(kid string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.keys, kid)
+	if j.activeKid == kid {
+		j.activeKid = ""
+	}
+}
+
+// SetActiveKey 切换签发密钥
+func (j *JWT) SetActiveKey(kid string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.keys[kid]; !ok {
+		return ErrKeyNotFound
+	}
+	j.activeKid = kid
+	return nil
+}
+
+// Issue 签发 token, 未设置的 iss/iat/exp/jti 自动填充
+func (j *JWT) Issue(claims Claims) (string, error) {
+	j.mu.RLock()
+	key, ok := j.keys[j.activeKid]
+	audience := j.audience
+	j.mu.RUnlock()
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	now := comm.Now()
+	if claims.Issuer == "" {
+		claims.Issuer = j.issuer
+	}
+	if claims.Audience == "" {
+		claims.Audience = audience
+	}
+	if claims.IssuedAt == 0 {
+		claims.IssuedAt = now.Unix()
+	}
+	if claims.ExpiresAt == 0 && j.expires > 0 {
+		claims.ExpiresAt = now.Add(j.expires).Unix()
+	}
+	if claims.Id == "" {
+		claims.Id = util.RandStr(16, false)
+	}
+	headerData, err := json.Marshal(header{Alg: key.Alg, Typ: "JWT", Kid: key.Kid})
+	if err != nil {
+		return "", err
+	}
+	claimsData, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodeSegment(headerData) + "." + encodeSegment(claimsData)
+	signature, err := sign(key, signingInput)
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// Verify 验证 token 签名与有效期
+func (j *JWT) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenInvalid
+	}
+	headerData, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+	h := header{}
+	if err = json.Unmarshal(headerData, &h); err != nil {
+		return nil, ErrTokenInvalid
+	}
+	j.mu.RLock()
+	kid := h.Kid
+	if kid == "" {
+		kid = j.activeKid
+	}
+	key, ok := j.keys[kid]
+	audience := j.audience
+	j.mu.RUnlock()
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	// 算法必须与密钥一致, 防止篡改 alg 绕过验签
+	if h.Alg != key.Alg {
+		return nil, ErrTokenInvalid
+	}
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+	if err = verify(key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, ErrTokenInvalid
+	}
+	claimsData, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+	claims := &Claims{}
+	if err = json.Unmarshal(claimsData, claims); err != nil {
+		return nil, ErrTokenInvalid
+	}
+	now := comm.Now()
+	if claims.ExpiresAt > 0 && now.Add(-j.leeway).Unix() > claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	if claims.NotBefore > 0 && now.Add(j.leeway).Unix() < claims.NotBefore {
+		return nil, ErrTokenInvalid
+	}
+	if j.issuer != "" && claims.Issuer != j.issuer {
+		return nil, ErrTokenInvalid
+	}
+	if audience != "" && claims.Audience != audience {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+func sign(key *Key, signingInput string) ([]byte, error) {
+	switch key.Alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write([]byte(signingInput))
+		return mac.Sum(nil), nil
+	case AlgRS256:
+		if key.PrivateKey == nil {
+			return nil, errors.New("签发密钥缺少私钥: " + key.Kid)
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.SignPKCS1v15(rand.Reader, key.PrivateKey, crypto.SHA256, digest[:])
+	}
+	return nil, ErrAlgNotSupport
+}
+
+func verify(key *Key, signingInput string, signature []byte) error {
+	switch key.Alg {
+	case AlgHS256:
+		expected, _ := sign(key, signingInput)
+		if !hmac.Equal(expected, signature) {
+			return ErrTokenInvalid
+		}
+		return nil
+	case AlgRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(key.PublicKey, crypto.SHA256, digest[:], signature)
+	}
+	return ErrAlgNotSupport
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(seg string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(seg)
+}
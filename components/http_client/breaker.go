@@ -0,0 +1,235 @@
+package http_client
+
+import (
+	"catuan/comm"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// BreakerConfig 熔断配置, 零值字段使用默认值
+type BreakerConfig struct {
+	FailureThreshold int           // 连续失败次数达到后熔断, 默认 5
+	OpenTimeout      time.Duration // 熔断持续时间, 之后进入半开状态, 默认 30 秒
+	HalfOpenRequests int           // 半开状态允许同时进行的探测请求数, 默认 1
+	SuccessThreshold int           // 半开状态连续成功次数达到后恢复, 默认 1
+
+	// IsFailure 判断请求是否失败, 默认网络错误或 5xx 为失败
+	// 调用方自身 context 已取消或超时的请求不经过 IsFailure, 既不计为失败也不计为成功; client 超时计为失败
+	IsFailure func(resp *http.Response, err error) bool
+	// Fallback 熔断时的降级处理, 默认返回 ErrCircuitOpen
+	Fallback func(req *http.Request) (*http.Response, error)
+	// OnStateChange 状态变化通知
+	OnStateChange func(host string, from State, to State)
+}
+
+func (cfg *BreakerConfig) setDefaults() {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = time.Second * 30
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.SuccessThreshold <= 0 {
+		cfg.SuccessThreshold = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= http.StatusInternalServerError
+		}
+	}
+}
+
+// Breaker 按 host 熔断
+type Breaker struct {
+	cfg   BreakerConfig
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+}
+
+type hostBreaker struct {
+	state      State
+	generation uint64 // 状态变化时递增, 忽略上一状态下发起的请求结果
+	failures   int
+	successes  int
+	inflight   int
+	openedAt   time.Time
+}
+
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	cfg.setDefaults()
+	return &Breaker{
+		cfg:   cfg,
+		hosts: make(map[string]*hostBreaker),
+	}
+}
+
+// State host 当前的熔断状态
+func (b *Breaker) State(host string) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if hb, ok := b.hosts[host]; ok {
+		b.refresh(host, hb, comm.Now())
+		return hb.state
+	}
+	return StateClosed
+}
+
+// Allow 检查是否允许请求, 允许时返回的 done 需在请求结束后调用
+func (b *Breaker) Allow(host string) (done func(success bool), err error) {
+	finish, err := b.allow(host)
+	if err != nil {
+		return nil, err
+	}
+	return func(success bool) {
+		finish(true, success)
+	}, nil
+}
+
+// allow finish 的 counted 为 false 时只归还半开状态的探测名额, 不记录结果
+func (b *Breaker) allow(host string) (finish func(counted bool, success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	hb, ok := b.hosts[host]
+	if !ok {
+		hb = &hostBreaker{}
+		b.hosts[host] = hb
+	}
+	b.refresh(host, hb, comm.Now())
+	switch hb.state {
+	case StateOpen:
+		return nil, ErrCircuitOpen
+	case StateHalfOpen:
+		if hb.inflight >= b.cfg.HalfOpenRequests {
+			return nil, ErrCircuitOpen
+		}
+	}
+	hb.inflight++
+	generation := hb.generation
+	return func(counted bool, success bool) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if hb.generation != generation {
+			return
+		}
+		hb.inflight--
+		if counted {
+			b.record(host, hb, success)
+		}
+	}, nil
+}
+
+// refresh 熔断超时后进入半开状态, 需持有 mu
+func (b *Breaker) refresh(host string, hb *hostBreaker, now time.Time) {
+	if hb.state == StateOpen && now.Sub(hb.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(host, hb, StateHalfOpen)
+	}
+}
+
+// record 需持有 mu
+func (b *Breaker) record(host string, hb *hostBreaker, success bool) {
+	switch hb.state {
+	case StateClosed:
+		if success {
+			hb.failures = 0
+			return
+		}
+		hb.failures++
+		if hb.failures >= b.cfg.FailureThreshold {
+			b.setState(host, hb, StateOpen)
+		}
+	case StateHalfOpen:
+		if !success {
+			b.setState(host, hb, StateOpen)
+			return
+		}
+		hb.successes++
+		if hb.successes >= b.cfg.SuccessThreshold {
+			b.setState(host, hb, StateClosed)
+		}
+	}
+}
+
+// setState 需持有 mu
+func (b *Breaker) setState(host string, hb *hostBreaker, state State) {
+	from := hb.state
+	hb.state = state
+	hb.generation++
+	hb.failures = 0
+	hb.successes = 0
+	hb.inflight = 0
+	if state == StateOpen {
+		hb.openedAt = comm.Now()
+	}
+	if b.cfg.OnStateChange != nil {
+		go b.cfg.OnStateChange(host, from, state)
+	}
+}
+
+// BreakerTransport 带熔断的 RoundTripper
+type BreakerTransport struct {
+	Base    http.RoundTripper
+	Breaker *Breaker
+}
+
+func NewBreakerTransport(base http.RoundTripper, cfg BreakerConfig) *BreakerTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &BreakerTransport{
+		Base:    base,
+		Breaker: NewBreaker(cfg),
+	}
+}
+
+func (t *BreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	finish, err := t.Breaker.allow(req.URL.Host)
+	if err != nil {
+		if t.Breaker.cfg.Fallback != nil {
+			return t.Breaker.cfg.Fallback(req)
+		}
+		return nil, err
+	}
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil && callerDone(req) {
+		// 调用方取消或超时, 与上游是否可用无关
+		finish(false, false)
+		return resp, err
+	}
+	finish(true, !t.Breaker.cfg.IsFailure(resp, err))
+	return resp, err
+}
+
+// callerDone 调用方自身的 context 是否已结束
+// 经 Builder 或 HttpClient 发出的请求可取得调用方的 context, 其他情况无法区分 client 超时, 只排除取消
+func callerDone(req *http.Request) bool {
+	if caller, ok := req.Context().Value(callerContextKey{}).(context.Context); ok {
+		return caller.Err() != nil
+	}
+	return errors.Is(req.Context().Err(), context.Canceled)
+}
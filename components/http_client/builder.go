@@ -0,0 +1,322 @@
+package http_client
+
+import (
+	"catuan/components/tracing"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// RetryPolicy 重试策略, 仅重试幂等请求(GET/HEAD/OPTIONS/PUT/DELETE 或携带 Idempotency-Key 的请求)
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数
+	BaseDelay  time.Duration // 首次重试的等待时间, 之后指数增长, 默认 100 毫秒
+	MaxDelay   time.Duration // 最长等待时间, 默认 2 秒
+	// RetryOn 判断是否需要重试, 默认网络错误及 429/502/503/504 时重试
+	RetryOn func(resp *http.Response, err error) bool
+}
+
+// Builder 创建 http.Client
+type Builder struct {
+	timeout             time.Duration
+	hostTimeouts        map[string]time.Duration
+	proxy               string
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+	retry               *RetryPolicy
+	breaker             *BreakerConfig
+	hooks               []HookFunc
+	captureBody         bool
+	maxBodySize         int
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		timeout:      time.Second * 5,
+		hostTimeouts: make(map[string]time.Duration),
+	}
+}
+
+// Timeout 单次调用的总超时时间(包含重试, 重定向后重新计算), 0 为不限制
+func (b *Builder) Timeout(timeout time.Duration) *Builder {
+	b.timeout = timeout
+	return b
+}
+
+// HostTimeout 指定 host 每次请求的超时时间
+func (b *Builder) HostTimeout(host string, timeout time.Duration) *Builder {
+	b.hostTimeouts[host] = timeout
+	return b
+}
+
+// Proxy 代理地址, 例如 http://127.0.0.1:8888
+func (b *Builder) Proxy(proxy string) *Builder {
+	b.proxy = proxy
+	return b
+}
+
+func (b *Builder) MaxIdleConns(n int) *Builder {
+	b.maxIdleConns = n
+	return b
+}
+
+func (b *Builder) MaxIdleConnsPerHost(n int) *Builder {
+	b.maxIdleConnsPerHost = n
+	return b
+}
+
+func (b *Builder) MaxConnsPerHost(n int) *Builder {
+	b.maxConnsPerHost = n
+	return b
+}
+
+func (b *Builder) IdleConnTimeout(timeout time.Duration) *Builder {
+	b.idleConnTimeout = timeout
+	return b
+}
+
+func (b *Builder) Retry(policy RetryPolicy) *Builder {
+	b.retry = &policy
+	return b
+}
+
+func (b *Builder) Breaker(cfg BreakerConfig) *Builder {
+	b.breaker = &cfg
+	return b
+}
+
+// Hooks 每次请求(包括重试)完成后调用, 例如 LoggingHook / DefaultMetrics.Hook
+func (b *Builder) Hooks(hooks ...HookFunc) *Builder {
+	b.hooks = append(b.hooks, hooks...)
+	return b
+}
+
+// CaptureBody 记录请求与响应内容供 hook 使用, maxSize 为记录的长度上限
+func (b *Builder) CaptureBody(maxSize int) *Builder {
+	b.captureBody = true
+	b.maxBodySize = maxSize
+	return b
+}
+
+// Build 请求依次经过 总超时 -> 重试 -> trace -> hook -> 熔断 -> host 超时 -> 连接池
+func (b *Builder) Build() (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConnsPerHost:   b.maxIdleConnsPerHost,
+		MaxConnsPerHost:       b.maxConnsPerHost,
+	}
+	if b.proxy != "" {
+		proxyUrl, err := url.Parse(b.proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+	if b.maxIdleConns > 0 {
+		transport.MaxIdleConns = b.maxIdleConns
+	}
+	if b.idleConnTimeout > 0 {
+		transport.IdleConnTimeout = b.idleConnTimeout
+	}
+	var rt http.RoundTripper = transport
+	if len(b.hostTimeouts) > 0 {
+		hostTimeouts := make(map[string]time.Duration, len(b.hostTimeouts))
+		for host, timeout := range b.hostTimeouts {
+			hostTimeouts[host] = timeout
+		}
+		rt = &hostTimeoutTransport{base: rt, timeouts: hostTimeouts}
+	}
+	// 熔断在 host 超时之外, host 超时计为失败, 调用方自身的取消与超时不计入
+	if b.breaker != nil {
+		rt = NewBreakerTransport(rt, *b.breaker)
+	}
+	if len(b.hooks) > 0 {
+		rt = &HookTransport{
+			Base:        rt,
+			Hooks:       append([]HookFunc{}, b.hooks...),
+			CaptureBody: b.captureBody,
+			MaxBodySize: b.maxBodySize,
+		}
+	}
+	rt = &tracing.Transport{Base: rt}
+	if b.retry != nil && b.retry.MaxRetries > 0 {
+		rt = newRetryTransport(rt, *b.retry)
+	}
+	return &http.Client{
+		Transport: &timeoutTransport{base: rt, timeout: b.timeout},
+	}, nil
+}
+
+type callerContextKey struct{}
+
+// timeoutTransport 总超时, 不使用 http.Client.Timeout, 以便熔断区分 client 超时与调用方自身的取消和超时
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := context.WithValue(req.Context(), callerContextKey{}, req.Context())
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req.WithContext(ctx))
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// hostTimeoutTransport 按 host 设置超时
+type hostTimeoutTransport struct {
+	base     http.RoundTripper
+	timeouts map[string]time.Duration
+}
+
+func (t *hostTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout, ok := t.timeouts[req.URL.Host]
+	if !ok {
+		timeout, ok = t.timeouts[req.URL.Hostname()]
+	}
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody 读取完成关闭时取消 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryTransport 失败后按指数退避加随机抖动重试
+type retryTransport struct {
+	base   http.RoundTripper
+	policy RetryPolicy
+}
+
+func newRetryTransport(base http.RoundTripper, policy RetryPolicy) *retryTransport {
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = time.Millisecond * 100
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = time.Second * 2
+	}
+	if policy.RetryOn == nil {
+		policy.RetryOn = defaultRetryOn
+	}
+	return &retryTransport{base: base, policy: policy}
+}
+
+func defaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	canRetry := isIdempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if !canRetry || attempt >= t.policy.MaxRetries || !t.policy.RetryOn(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(t.backoff(attempt))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// backoff 指数退避, 在 [0, delay) 之间随机
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	delay := t.policy.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > t.policy.MaxDelay {
+		delay = t.policy.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+var (
+	clientsMu sync.RWMutex
+	clients   = make(map[string]*http.Client)
+)
+
+// Register 注册命名的 client
+func Register(name string, client *http.Client) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	clients[name] = client
+}
+
+// Get 获取命名的 client, 不存在时返回 HttpClient
+func Get(name string) *http.Client {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	if client, ok := clients[name]; ok {
+		return client
+	}
+	return HttpClient
+}
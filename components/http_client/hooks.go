@@ -0,0 +1,203 @@
+package http_client
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RequestInfo 一次请求的信息, 开启 CaptureBody 后包含请求与响应内容
+type RequestInfo struct {
+	Request  *http.Request
+	Response *http.Response // 请求失败时为 nil
+	Err      error
+	Latency  time.Duration
+	ReqBody  []byte
+	RespBody []byte
+}
+
+// Status 响应状态码, 请求失败时为 0
+func (info *RequestInfo) Status() int {
+	if info.Response == nil {
+		return 0
+	}
+	return info.Response.StatusCode
+}
+
+// Failed 网络错误或 5xx
+func (info *RequestInfo) Failed() bool {
+	return info.Err != nil || info.Status() >= http.StatusInternalServerError
+}
+
+// HookFunc 请求完成后调用
+type HookFunc func(info *RequestInfo)
+
+// HookTransport 请求完成后依次调用 hook
+type HookTransport struct {
+	Base        http.RoundTripper
+	Hooks       []HookFunc
+	CaptureBody bool
+	MaxBodySize int // 记录的内容长度上限, 默认 4KB
+}
+
+func (t *HookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	maxSize := t.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = 4096
+	}
+	info := &RequestInfo{Request: req}
+	if t.CaptureBody && req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				info.ReqBody, _ = io.ReadAll(io.LimitReader(body, int64(maxSize)))
+				body.Close()
+			}
+		} else {
+			// 无法重复读取时读出后重新设置
+			data, err := io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = io.NopCloser(bytes.NewReader(data))
+			info.Request = req
+			info.ReqBody = data
+			if len(data) > maxSize {
+				info.ReqBody = data[:maxSize]
+			}
+		}
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	info.Latency = time.Since(start)
+	info.Response = resp
+	info.Err = err
+	if t.CaptureBody && resp != nil && resp.Body != nil {
+		head, _ := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)))
+		info.RespBody = head
+		resp.Body = &multiReadCloser{Reader: io.MultiReader(bytes.NewReader(head), resp.Body), Closer: resp.Body}
+	}
+	for _, hook := range t.Hooks {
+		hook(info)
+	}
+	return resp, err
+}
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// LogOption 请求日志配置
+type LogOption struct {
+	Body     bool      // 是否记录请求与响应内容, 需开启 HookTransport.CaptureBody
+	Redactor *Redactor // 为空时使用 DefaultRedactFields
+}
+
+// LoggingHook 记录请求方法/地址/状态/耗时, 成功的请求为 debug 级别, 失败为 warn 级别
+func LoggingHook(opt LogOption) HookFunc {
+	redactor := opt.Redactor
+	if redactor == nil {
+		redactor = NewRedactor(DefaultRedactFields...)
+	}
+	return func(info *RequestInfo) {
+		fields := logrus.Fields{
+			"method":  info.Request.Method,
+			"url":     redactor.URL(info.Request.URL),
+			"status":  info.Status(),
+			"latency": info.Latency.Milliseconds(),
+		}
+		if opt.Body {
+			fields["reqBody"] = redactor.Body(info.Request.Header.Get("Content-Type"), info.ReqBody)
+			if info.Response != nil {
+				fields["respBody"] = redactor.Body(info.Response.Header.Get("Content-Type"), info.RespBody)
+			}
+		}
+		entry := logrus.WithFields(fields)
+		if info.Err != nil {
+			entry.Warn(info.Err.Error())
+			return
+		}
+		if info.Failed() {
+			entry.Warn("http request failed")
+			return
+		}
+		entry.Debug("http request")
+	}
+}
+
+// DefaultLatencyBuckets 耗时分布区间 秒
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HostStat 单个 host 的请求统计
+type HostStat struct {
+	Requests     uint64
+	Errors       uint64
+	LatencySum   float64  // 秒
+	BucketCounts []uint64 // 与 Buckets 对应的累计数量
+	Buckets      []float64
+}
+
+// Metrics 按 host 统计请求数量/失败数量/耗时分布
+type Metrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	hosts   map[string]*HostStat
+}
+
+// DefaultMetrics HttpClient 使用的统计
+var DefaultMetrics = NewMetrics(DefaultLatencyBuckets)
+
+func NewMetrics(buckets []float64) *Metrics {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return &Metrics{
+		buckets: sorted,
+		hosts:   make(map[string]*HostStat),
+	}
+}
+
+// Hook 统计 hook
+func (m *Metrics) Hook(info *RequestInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	host := info.Request.URL.Host
+	stat, ok := m.hosts[host]
+	if !ok {
+		stat = &HostStat{BucketCounts: make([]uint64, len(m.buckets)), Buckets: m.buckets}
+		m.hosts[host] = stat
+	}
+	seconds := info.Latency.Seconds()
+	stat.Requests++
+	stat.LatencySum += seconds
+	if info.Failed() {
+		stat.Errors++
+	}
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			stat.BucketCounts[i]++
+		}
+	}
+}
+
+// Snapshot 各 host 当前的统计
+func (m *Metrics) Snapshot() map[string]HostStat {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]HostStat, len(m.hosts))
+	for host, stat := range m.hosts {
+		copied := *stat
+		copied.BucketCounts = append([]uint64{}, stat.BucketCounts...)
+		snapshot[host] = copied
+	}
+	return snapshot
+}
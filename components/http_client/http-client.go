@@ -1,12 +1,27 @@
 package http_client
 
 import (
+	"catuan/components/tracing"
 	"net/http"
 	"time"
 )
 
 var (
+	// DefaultBreaker HttpClient 使用的熔断器, 可用于查询各 host 的熔断状态
+	DefaultBreaker = NewBreaker(BreakerConfig{})
+
 	HttpClient = &http.Client{
-		Timeout: time.Second * 5,
+		Transport: &timeoutTransport{
+			timeout: time.Second * 5,
+			base: &tracing.Transport{
+				Base: &HookTransport{
+					Base: &BreakerTransport{
+						Base:    http.DefaultTransport,
+						Breaker: DefaultBreaker,
+					},
+					Hooks: []HookFunc{LoggingHook(LogOption{}), DefaultMetrics.Hook},
+				},
+			},
+		},
 	}
 )
@@ -0,0 +1,115 @@
+package http_client
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "***"
+
+// DefaultRedactFields 默认脱敏的字段
+var DefaultRedactFields = []string{"secret", "access_token", "js_code", "openid", "session_key", "sign", "paySign"}
+
+// Redactor 对 url 参数与请求/响应内容中的敏感字段脱敏, 字段名不区分大小写
+type Redactor struct {
+	fields  map[string]bool
+	xmlReg  *regexp.Regexp
+	jsonReg *regexp.Regexp
+	formReg *regexp.Regexp
+}
+
+func NewRedactor(fields ...string) *Redactor {
+	r := &Redactor{fields: make(map[string]bool)}
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = true
+		names = append(names, regexp.QuoteMeta(field))
+	}
+	if len(names) > 0 {
+		joined := strings.Join(names, "|")
+		// 未闭合的标签或字符串(内容被截断)脱敏到结尾
+		r.xmlReg = regexp.MustCompile(`(?is)<(` + joined + `)(?:\s[^>]*)?>.*?(?:</(?:` + joined + `)>|$)`)
+		r.jsonReg = regexp.MustCompile(`(?is)("(?:` + joined + `)"\s*:\s*)(?:"(?:[^"\\]|\\.?)*(?:"|$)|[^,}\]\s]*)`)
+		r.formReg = regexp.MustCompile(`(?i)(^|[&?\s])(` + joined + `)=[^&\s]*`)
+	}
+	return r
+}
+
+func (r *Redactor) sensitive(field string) bool {
+	return r.fields[strings.ToLower(field)]
+}
+
+// URL 脱敏 query 参数
+func (r *Redactor) URL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	copied := *u
+	copied.RawQuery = r.query(u.RawQuery)
+	return copied.String()
+}
+
+// query 保持参数原有顺序, 仅替换敏感字段的值
+func (r *Redactor) query(rawQuery string) string {
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && r.sensitive(name) {
+			pairs[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Body 根据内容类型脱敏, 支持 json/xml/form
+// 解析失败(包括超出记录长度被截断)或其他类型时按字段名匹配 json/xml/form 三种写法脱敏
+func (r *Redactor) Body(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	switch {
+	case strings.Contains(contentType, "json") || (contentType == "" && json.Valid(body)):
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return r.pattern(string(body))
+		}
+		redacted, err := json.Marshal(r.value(v))
+		if err != nil {
+			return r.pattern(string(body))
+		}
+		return string(redacted)
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		return r.query(string(body))
+	}
+	return r.pattern(string(body))
+}
+
+// pattern 不解析内容, 按字段名替换
+func (r *Redactor) pattern(body string) string {
+	if r.xmlReg == nil {
+		return body
+	}
+	body = r.jsonReg.ReplaceAllString(body, `${1}"`+redactedValue+`"`)
+	body = r.xmlReg.ReplaceAllString(body, "<$1>"+redactedValue+"</$1>")
+	return r.formReg.ReplaceAllString(body, "${1}${2}="+redactedValue)
+}
+
+func (r *Redactor) value(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, item := range val {
+			if r.sensitive(key) {
+				val[key] = redactedValue
+			} else {
+				val[key] = r.value(item)
+			}
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = r.value(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,210 @@
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Bundle 多语言消息, 每个语言一个文件, 文件名即语言, 例如 zh-CN.yaml / en.json
+type Bundle struct {
+	mu            sync.RWMutex
+	defaultLocale string
+	messages      map[string]map[string]string // locale -> key -> message
+}
+
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		defaultLocale: defaultLocale,
+		messages:      make(map[string]map[string]string),
+	}
+}
+
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// AddMessages 添加消息, 已存在的 key 被覆盖
+func (b *Bundle) AddMessages(locale string, messages map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.messages[locale] == nil {
+		b.messages[locale] = make(map[string]string)
+	}
+	for key, message := range messages {
+		b.messages[locale][key] = message
+	}
+}
+
+// LoadFile 加载 yaml/json 文件, 嵌套的 key 以 . 连接
+func (b *Bundle) LoadFile(file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(file))
+	raw := make(map[string]any)
+	switch ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".json":
+		err = json.Unmarshal(content, &raw)
+	default:
+		return errors.New("不支持的消息文件格式: " + file)
+	}
+	if err != nil {
+		return err
+	}
+	messages := make(map[string]string)
+	flatten("", raw, messages)
+	b.AddMessages(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), messages)
+	return nil
+}
+
+// LoadDir 加载目录下所有 yaml/json 文件
+func (b *Bundle) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if err = b.LoadFile(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func flatten(prefix string, raw map[string]any, out map[string]string) {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(key, v, out)
+		case string:
+			out[key] = v
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// Locales 已加载的语言
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	locales := make([]string, 0, len(b.messages))
+	for locale := range b.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Has 是否支持该语言
+func (b *Bundle) Has(locale string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.messages[locale]
+	return ok
+}
+
+// Translate 翻译 key, 当前语言不存在时使用默认语言, 均不存在时返回 false
+func (b *Bundle) Translate(locale, key string, args map[string]any) (string, bool) {
+	b.mu.RLock()
+	message, ok := b.messages[locale][key]
+	if !ok {
+		message, ok = b.messages[b.defaultLocale][key]
+	}
+	b.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return Format(message, args), true
+}
+
+// T 翻译 key, 不存在时返回 key
+func (b *Bundle) T(locale, key string, args map[string]any) string {
+	if message, ok := b.Translate(locale, key, args); ok {
+		return message
+	}
+	return key
+}
+
+// Match 根据 Accept-Language 选择支持的语言, 先完全匹配再按主语言匹配, 无匹配时返回默认语言
+func (b *Bundle) Match(acceptLanguage string) string {
+	type langItem struct {
+		tag string
+		q   float64
+	}
+	items := make([]langItem, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		params := strings.Split(part, ";")
+		item := langItem{tag: strings.TrimSpace(params[0]), q: 1}
+		if item.tag == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					item.q = q
+				}
+			}
+		}
+		if item.q > 0 {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+	locales := b.Locales()
+	for _, item := range items {
+		if locale, ok := matchLocale(locales, item.tag); ok {
+			return locale
+		}
+	}
+	return b.defaultLocale
+}
+
+func matchLocale(locales []string, tag string) (string, bool) {
+	tag = strings.ReplaceAll(tag, "_", "-")
+	for _, locale := range locales {
+		if strings.EqualFold(locale, tag) {
+			return locale, true
+		}
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	for _, locale := range locales {
+		localeBase, _, _ := strings.Cut(locale, "-")
+		if strings.EqualFold(localeBase, base) {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// Format 替换消息中的 {name} 占位符
+func Format(message string, args map[string]any) string {
+	if len(args) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+	pairs := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
@@ -0,0 +1,90 @@
+package idempotency
+
+import (
+	"catuan/comm"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryRecord struct {
+	rec     Record
+	expires time.Time
+}
+
+// MemoryStore 内存幂等记录存储, 用于测试或单机部署
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryRecord
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]*memoryRecord),
+	}
+}
+
+func (s *MemoryStore) get(key string) *memoryRecord {
+	item, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	if comm.Now().After(item.expires) {
+		delete(s.records, key)
+		return nil
+	}
+	return item
+}
+
+func (s *MemoryStore) Begin(ctx context.Context, key string, lockTTL time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item := s.get(key); item != nil {
+		rec := item.rec
+		return &rec, false, nil
+	}
+	pending := newPendingRecord()
+	s.records[key] = &memoryRecord{
+		rec:     *pending,
+		expires: comm.Now().Add(lockTTL),
+	}
+	return pending, true, nil
+}
+
+// held key 仍由 token 占用, 需持有 mu
+func (s *MemoryStore) held(key string, token string) bool {
+	item := s.get(key)
+	return item != nil && item.rec.Status == StatusPending && item.rec.Token == token
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, token string, resp *comm.RespResult, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.held(key, token) {
+		return ErrLockLost
+	}
+	s.records[key] = &memoryRecord{
+		rec:     *newDoneRecord(resp),
+		expires: comm.Now().Add(ttl),
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held(key, token) {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item := s.get(key); item != nil {
+		rec := item.rec
+		return &rec, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,106 @@
+package idempotency
+
+import (
+	"catuan/comm"
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// completeScript 仍由 token 占用时写入结果
+var completeScript = redis.NewScript(`
+local val = redis.call('GET', KEYS[1])
+if not val then
+	return 0
+end
+local rec = cjson.decode(val)
+if rec.status ~= 'pending' or rec.token ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// releaseScript 仍由 token 占用时删除
+var releaseScript = redis.NewScript(`
+local val = redis.call('GET', KEYS[1])
+if not val then
+	return 0
+end
+local rec = cjson.decode(val)
+if rec.status ~= 'pending' or rec.token ~= ARGV[1] then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "idempotency:"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Begin(ctx context.Context, key string, lockTTL time.Duration) (*Record, bool, error) {
+	pending := newPendingRecord()
+	val, _ := json.Marshal(pending)
+	ok, err := s.client.SetNX(ctx, s.prefix+key, val, lockTTL).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		return pending, true, nil
+	}
+	rec, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if rec == nil {
+		// 记录刚好过期, 重新占用
+		return s.Begin(ctx, key, lockTTL)
+	}
+	return rec, false, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, token string, resp *comm.RespResult, ttl time.Duration) error {
+	val, err := json.Marshal(newDoneRecord(resp))
+	if err != nil {
+		return err
+	}
+	ok, err := completeScript.Run(ctx, s.client, []string{s.prefix + key}, token, val, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string, token string) error {
+	return releaseScript.Run(ctx, s.client, []string{s.prefix + key}, token).Err()
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (*Record, error) {
+	val, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rec := &Record{}
+	if err = json.Unmarshal(val, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
@@ -0,0 +1,68 @@
+package idempotency
+
+import (
+	"catuan/comm"
+	"catuan/util"
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+)
+
+// ErrLockLost 占用已过期并被其他请求取得, 结果不再保存
+var ErrLockLost = errors.New("幂等记录已被其他请求占用")
+
+// Record 幂等记录, 处理中时 Resp 为空, Token 标识当前占用者
+type Record struct {
+	Status string           `json:"status"`
+	Token  string           `json:"token,omitempty"`
+	Resp   *comm.RespResult `json:"resp,omitempty"`
+
+	// RespResult 中不序列化的字段, 回放时恢复 http 状态码和消息翻译
+	RespStatus int            `json:"respStatus,omitempty"`
+	RespKey    string         `json:"respKey,omitempty"`
+	RespArgs   map[string]any `json:"respArgs,omitempty"`
+}
+
+func newPendingRecord() *Record {
+	return &Record{Status: StatusPending, Token: util.RandStr(24, false)}
+}
+
+func newDoneRecord(resp *comm.RespResult) *Record {
+	respCopy := *resp
+	return &Record{
+		Status:     StatusDone,
+		Resp:       &respCopy,
+		RespStatus: resp.Status,
+		RespKey:    resp.Key,
+		RespArgs:   resp.Args,
+	}
+}
+
+// Response 回放的响应结果
+func (r *Record) Response() *comm.RespResult {
+	if r.Resp == nil {
+		return nil
+	}
+	resp := *r.Resp
+	resp.Status = r.RespStatus
+	resp.Key = r.RespKey
+	resp.Args = r.RespArgs
+	return &resp
+}
+
+// Store 幂等记录存储
+type Store interface {
+	// Begin 占用 key, 成功时返回处理中的记录, 其 Token 用于 Complete/Release; 已被占用时返回已有记录且 acquired 为 false
+	Begin(ctx context.Context, key string, lockTTL time.Duration) (rec *Record, acquired bool, err error)
+	// Complete 保存处理结果, token 与当前占用者不一致时返回 ErrLockLost
+	Complete(ctx context.Context, key string, token string, resp *comm.RespResult, ttl time.Duration) error
+	// Release 放弃占用, 处理失败时调用, 允许客户端重试; token 不一致时不做处理
+	Release(ctx context.Context, key string, token string) error
+	// Get 读取记录, 不存在时返回 nil, nil
+	Get(ctx context.Context, key string) (*Record, error)
+}
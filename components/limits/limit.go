@@ -2,36 +2,178 @@ package limits
 
 import (
 	"catuan/comm"
+	"context"
 	"sync"
+	"time"
 )
 
 // UniLimit 唯一限制器 单 goroutine 限制访问
+// 设置 ttl 后占用超时自动释放; 设置 max 后最多同时占用 max 个 key
 type UniLimit[K comm.KeyAble] struct {
 	mu   sync.Mutex
-	dest map[K]bool
+	dest map[K]*uniEntry
+	ttl  time.Duration
+	max  int
+
+	freed chan struct{} // 有 key 释放时关闭并重建, 用于唤醒等待空位的 Acquire
+}
+
+type uniEntry struct {
+	expires  time.Time // 零值表示不过期
+	released chan struct{}
 }
 
 func NewUniLimit[K comm.KeyAble]() *UniLimit[K] {
+	return NewBoundedUniLimit[K](0, 0)
+}
+
+// NewUniLimitWithTTL 占用超过 ttl 后自动释放, 防止异常流程未 Release 导致 key 永久占用
+func NewUniLimitWithTTL[K comm.KeyAble](ttl time.Duration) *UniLimit[K] {
+	return NewBoundedUniLimit[K](0, ttl)
+}
+
+// NewBoundedUniLimit 最多同时占用 max 个 key, 超出时 Check 返回 false, max 为 0 不限制
+func NewBoundedUniLimit[K comm.KeyAble](max int, ttl time.Duration) *UniLimit[K] {
 	return &UniLimit[K]{
-		dest: make(map[K]bool),
-		mu:   sync.Mutex{},
+		dest:  make(map[K]*uniEntry),
+		mu:    sync.Mutex{},
+		ttl:   ttl,
+		max:   max,
+		freed: make(chan struct{}),
 	}
 }
 
 func (l *UniLimit[K]) Check(key K) bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	_, ok := l.dest[key]
-	if !ok {
-		l.dest[key] = true
+	return l.tryLock(key, comm.Now())
+}
+
+// tryLock 需持有 mu
+func (l *UniLimit[K]) tryLock(key K, now time.Time) bool {
+	if e, ok := l.dest[key]; ok {
+		if !l.expired(e, now) {
+			return false
+		}
+		l.remove(key, e)
+	}
+	if l.max > 0 && len(l.dest) >= l.max {
+		l.purge(now)
+		if len(l.dest) >= l.max {
+			return false
+		}
+	}
+	e := &uniEntry{released: make(chan struct{})}
+	if l.ttl > 0 {
+		e.expires = now.Add(l.ttl)
+	}
+	l.dest[key] = e
+	return true
+}
+
+func (l *UniLimit[K]) expired(e *uniEntry, now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// remove 需持有 mu
+func (l *UniLimit[K]) remove(key K, e *uniEntry) {
+	delete(l.dest, key)
+	close(e.released)
+	close(l.freed)
+	l.freed = make(chan struct{})
+}
+
+// purge 清理已过期的 key, 需持有 mu
+func (l *UniLimit[K]) purge(now time.Time) {
+	for key, e := range l.dest {
+		if l.expired(e, now) {
+			l.remove(key, e)
+		}
 	}
-	return !ok
 }
 
 func (l *UniLimit[K]) Release(key K) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if _, ok := l.dest[key]; ok {
-		delete(l.dest, key)
+	if e, ok := l.dest[key]; ok {
+		l.remove(key, e)
+	}
+}
+
+// Acquire 阻塞等待 key 空闲后占用, ctx 结束时返回 ctx.Err()
+func (l *UniLimit[K]) Acquire(ctx context.Context, key K) error {
+	for {
+		l.mu.Lock()
+		now := comm.Now()
+		if l.tryLock(key, now) {
+			l.mu.Unlock()
+			return nil
+		}
+		var wait chan struct{}
+		var expires time.Time
+		if e, ok := l.dest[key]; ok {
+			wait, expires = e.released, e.expires
+		} else {
+			// 占用数量已满, 等待任意 key 释放或过期
+			wait, expires = l.freed, l.earliestExpires()
+		}
+		l.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !expires.IsZero() {
+			timer = time.NewTimer(expires.Sub(now))
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-wait:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// earliestExpires 最早过期的时间, 需持有 mu
+func (l *UniLimit[K]) earliestExpires() time.Time {
+	var earliest time.Time
+	for _, e := range l.dest {
+		if !e.expires.IsZero() && (earliest.IsZero() || e.expires.Before(earliest)) {
+			earliest = e.expires
+		}
 	}
+	return earliest
+}
+
+// Do 占用 key 后执行 fn, 执行结束(包括 panic)后释放, key 已被占用时不执行并返回 false
+func (l *UniLimit[K]) Do(key K, fn func()) bool {
+	l.mu.Lock()
+	if !l.tryLock(key, comm.Now()) {
+		l.mu.Unlock()
+		return false
+	}
+	e := l.dest[key]
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		// 超时后 key 可能已被其他调用占用, 只释放自己的占用
+		if l.dest[key] == e {
+			l.remove(key, e)
+		}
+	}()
+	fn()
+	return true
+}
+
+// Len 当前占用的 key 数量, 包含已过期未清理的 key
+func (l *UniLimit[K]) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.dest)
 }
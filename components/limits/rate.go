@@ -0,0 +1,129 @@
+package limits
+
+import (
+	"catuan/comm"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	AlgorithmTokenBucket   = "tokenBucket"
+	AlgorithmSlidingWindow = "slidingWindow"
+)
+
+// RateLimiter 限流器, 不允许通过时返回需要等待的时间
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// TokenBucket 进程内令牌桶, 容量为 limit, 每 window 时间补满
+type TokenBucket struct {
+	mu      sync.Mutex
+	limit   float64
+	rate    float64 // 每纳秒补充的令牌数
+	buckets map[string]*bucket
+	sweepAt time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(limit int, window time.Duration) *TokenBucket {
+	return &TokenBucket{
+		limit:   float64(limit),
+		rate:    float64(limit) / float64(window),
+		buckets: make(map[string]*bucket),
+		sweepAt: comm.Now(),
+	}
+}
+
+func (t *TokenBucket) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := comm.Now()
+	t.sweep(now)
+	b, ok := t.buckets[key]
+	if !ok {
+		b = &bucket{tokens: t.limit, last: now}
+		t.buckets[key] = b
+	}
+	b.tokens = math.Min(t.limit, b.tokens+float64(now.Sub(b.last))*t.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration(math.Ceil((1 - b.tokens) / t.rate)), nil
+}
+
+// sweep 清理已补满的令牌桶
+func (t *TokenBucket) sweep(now time.Time) {
+	fullAfter := time.Duration(t.limit / t.rate)
+	if now.Sub(t.sweepAt) < fullAfter {
+		return
+	}
+	t.sweepAt = now
+	for key, b := range t.buckets {
+		if now.Sub(b.last) >= fullAfter {
+			delete(t.buckets, key)
+		}
+	}
+}
+
+// SlidingWindow 进程内滑动窗口, 任意 window 时间内最多 limit 次
+type SlidingWindow struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	logs    map[string][]time.Time
+	sweepAt time.Time
+}
+
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		limit:   limit,
+		window:  window,
+		logs:    make(map[string][]time.Time),
+		sweepAt: comm.Now(),
+	}
+}
+
+func (s *SlidingWindow) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := comm.Now()
+	s.sweep(now)
+	logs := s.trim(s.logs[key], now)
+	if len(logs) < s.limit {
+		s.logs[key] = append(logs, now)
+		return true, 0, nil
+	}
+	s.logs[key] = logs
+	return false, logs[0].Add(s.window).Sub(now), nil
+}
+
+// trim 移除窗口外的记录
+func (s *SlidingWindow) trim(logs []time.Time, now time.Time) []time.Time {
+	start := now.Add(-s.window)
+	i := 0
+	for i < len(logs) && !logs[i].After(start) {
+		i++
+	}
+	return logs[i:]
+}
+
+func (s *SlidingWindow) sweep(now time.Time) {
+	if now.Sub(s.sweepAt) < s.window {
+		return
+	}
+	s.sweepAt = now
+	for key, logs := range s.logs {
+		if len(s.trim(logs, now)) == 0 {
+			delete(s.logs, key)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package limits
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	mrand "math/rand"
+	"sync"
+	"time"
+)
+
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// DefaultLockTTL 未指定过期时间时使用
+const DefaultLockTTL = time.Second * 30
+
+// ErrLockLost 锁已过期或被其他持有者获取
+var ErrLockLost = errors.New("distributed lock lost")
+
+// RedisLock 基于 redis 的分布式锁, 用法与 UniLimit 一致: Check 成功后处理, 处理完成后 Release
+// Check/Acquire 返回本次获取的 Lock, 通过 Lock.Release 释放; 按 key 释放时同一进程内的其他协程可能误删锁, 因此释放需持有 Lock
+// 锁带有过期时间, 持有期间自动续期, 释放时校验持有者; 续期失败或锁被他人获取时关闭 Lock.Lost
+type RedisLock struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// Lock 一次成功获取的锁, 只能由获取者释放
+type Lock struct {
+	l     *RedisLock
+	key   string
+	token string
+	// ctx 续期使用, 不受获取时 ctx 影响, Release 时取消
+	ctx      context.Context
+	cancel   context.CancelFunc
+	lost     chan struct{}
+	lostOnce sync.Once
+	once     sync.Once
+}
+
+// NewRedisLock ttl <= 0 时使用 DefaultLockTTL
+func NewRedisLock(client *redis.Client, prefix string, ttl time.Duration) *RedisLock {
+	if prefix == "" {
+		prefix = "lock:"
+	}
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+	return &RedisLock{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Check 尝试获取锁, 不等待
+func (l *RedisLock) Check(ctx context.Context, key string) (*Lock, bool) {
+	lock, err := l.tryLock(ctx, key)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"tip": "获取分布式锁异常",
+			"key": key,
+		}).Error(err.Error())
+	}
+	return lock, lock != nil
+}
+
+// Acquire 阻塞获取锁, 直到成功或 ctx 结束
+func (l *RedisLock) Acquire(ctx context.Context, key string) (*Lock, error) {
+	wait := time.Millisecond * 20
+	for {
+		lock, err := l.tryLock(ctx, key)
+		if lock != nil {
+			return lock, nil
+		}
+		if err != nil && ctx.Err() == nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "获取分布式锁异常",
+				"key": key,
+			}).Error(err.Error())
+		}
+		// 随机退避, 避免多个实例同时重试
+		timer := time.NewTimer(wait + time.Duration(mrand.Int63n(int64(wait))))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if wait < time.Millisecond*500 {
+			wait *= 2
+		}
+	}
+}
+
+// AcquireTimeout 阻塞获取锁, 超时返回 context.DeadlineExceeded
+func (l *RedisLock) AcquireTimeout(ctx context.Context, key string, timeout time.Duration) (*Lock, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return l.Acquire(ctx, key)
+}
+
+func (l *RedisLock) tryLock(ctx context.Context, key string) (*Lock, error) {
+	token := newLockToken()
+	ok, err := l.client.SetNX(ctx, l.prefix+key, token, l.ttl).Result()
+	if err != nil || !ok {
+		return nil, err
+	}
+	lock := &Lock{l: l, key: key, token: token, lost: make(chan struct{})}
+	lock.ctx, lock.cancel = context.WithCancel(context.Background())
+	go lock.renew()
+	return lock, nil
+}
+
+// renew 持有期间每 1/3 过期时间续期一次, 锁被他人获取或超过过期时间未续期成功时标记为失效
+func (lock *Lock) renew() {
+	l := lock.l
+	interval := l.ttl / 3
+	if interval <= 0 {
+		interval = l.ttl
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-lock.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		res, err := renewScript.Run(lock.ctx, l.client, []string{l.prefix + lock.key}, lock.token, l.ttl.Milliseconds()).Int()
+		if lock.ctx.Err() != nil {
+			return
+		}
+		if err == nil && res == 1 {
+			renewed = time.Now()
+			continue
+		}
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "分布式锁续期异常",
+				"key": lock.key,
+			}).Error(err.Error())
+			if time.Since(renewed) < l.ttl {
+				continue
+			}
+		}
+		logrus.WithFields(logrus.Fields{
+			"tip": "分布式锁已失效",
+			"key": lock.key,
+		}).Warn(ErrLockLost.Error())
+		lock.setLost()
+		return
+	}
+}
+
+func (lock *Lock) setLost() {
+	lock.lostOnce.Do(func() {
+		close(lock.lost)
+	})
+}
+
+// Lost 锁失效时关闭, 持有者应停止依赖锁的处理
+func (lock *Lock) Lost() <-chan struct{} {
+	return lock.lost
+}
+
+// Err 锁失效后返回 ErrLockLost
+func (lock *Lock) Err() error {
+	select {
+	case <-lock.lost:
+		return ErrLockLost
+	default:
+		return nil
+	}
+}
+
+// Release 释放锁, 仅删除本次获取的锁, 锁已失效时返回 ErrLockLost, 重复调用无影响
+func (lock *Lock) Release(ctx context.Context) error {
+	var err error
+	lock.once.Do(func() {
+		lock.cancel()
+		l := lock.l
+		res, e := releaseScript.Run(ctx, l.client, []string{l.prefix + lock.key}, lock.token).Int()
+		if e != nil {
+			err = e
+			return
+		}
+		if res == 0 {
+			lock.setLost()
+			err = ErrLockLost
+		}
+	})
+	return err
+}
+
+// Key 锁的 key
+func (lock *Lock) Key() string {
+	return lock.key
+}
+
+// Token 本次获取的锁标识
+func (lock *Lock) Token() string {
+	return lock.token
+}
+
+func newLockToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,105 @@
+package limits
+
+import (
+	"catuan/comm"
+	"catuan/util"
+	"context"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"time"
+)
+
+var tokenBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local rate = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+return {allowed, wait}
+`)
+
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, tonumber(oldest[2]) + window - now}
+`)
+
+// RedisTokenBucket 基于 redis 的令牌桶, 多实例共享限额, redis 异常时放行并返回错误
+type RedisTokenBucket struct {
+	client *redis.Client
+	prefix string
+	limit  int
+	window time.Duration
+}
+
+func NewRedisTokenBucket(client *redis.Client, prefix string, limit int, window time.Duration) *RedisTokenBucket {
+	return &RedisTokenBucket{
+		client: client,
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (r *RedisTokenBucket) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	now := comm.Now().UnixMilli()
+	rate := float64(r.limit) / float64(r.window.Milliseconds())
+	res, err := tokenBucketScript.Run(ctx, r.client, []string{r.prefix + key},
+		now, r.limit, strconv.FormatFloat(rate, 'f', -1, 64)).Int64Slice()
+	if err != nil {
+		return true, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// RedisSlidingWindow 基于 redis 的滑动窗口, 多实例共享限额
+type RedisSlidingWindow struct {
+	client *redis.Client
+	prefix string
+	limit  int
+	window time.Duration
+}
+
+func NewRedisSlidingWindow(client *redis.Client, prefix string, limit int, window time.Duration) *RedisSlidingWindow {
+	return &RedisSlidingWindow{
+		client: client,
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (r *RedisSlidingWindow) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	now := comm.Now()
+	member := strconv.FormatInt(now.UnixNano(), 10) + util.RandStr(6, false)
+	res, err := slidingWindowScript.Run(ctx, r.client, []string{r.prefix + key},
+		now.UnixMilli(), r.window.Milliseconds(), r.limit, member).Int64Slice()
+	if err != nil {
+		return true, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
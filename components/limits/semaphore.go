@@ -0,0 +1,177 @@
+package limits
+
+import (
+	"catuan/comm"
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Semaphore 按 key 限制并发数量, 例如每个商户最多同时 3 个导出任务
+// 等待者按先后顺序获得许可
+type Semaphore[K comm.KeyAble] struct {
+	mu         sync.Mutex
+	capacity   int
+	capacities map[K]int
+	items      map[K]*semItem
+}
+
+type semItem struct {
+	holders int
+	waiters *list.List // *semWaiter
+}
+
+type semWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// SemaphoreStats 当前持有数量与排队数量
+type SemaphoreStats struct {
+	Capacity int `json:"capacity"`
+	Holders  int `json:"holders"`
+	Waiting  int `json:"waiting"`
+}
+
+// NewSemaphore capacity 为每个 key 默认的并发数量
+func NewSemaphore[K comm.KeyAble](capacity int) *Semaphore[K] {
+	return &Semaphore[K]{
+		capacity:   capacity,
+		capacities: make(map[K]int),
+		items:      make(map[K]*semItem),
+	}
+}
+
+// SetCapacity 单独设置 key 的并发数量, 调大时立即唤醒等待者
+func (s *Semaphore[K]) SetCapacity(key K, capacity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capacities[key] = capacity
+	if item, ok := s.items[key]; ok {
+		s.grant(key, item)
+	}
+}
+
+// capacityOf 需持有 mu
+func (s *Semaphore[K]) capacityOf(key K) int {
+	if capacity, ok := s.capacities[key]; ok {
+		return capacity
+	}
+	return s.capacity
+}
+
+// item 需持有 mu
+func (s *Semaphore[K]) item(key K) *semItem {
+	item, ok := s.items[key]
+	if !ok {
+		item = &semItem{waiters: list.New()}
+		s.items[key] = item
+	}
+	return item
+}
+
+// TryAcquire 尝试获取许可, 不等待; 有排队者时返回 false
+func (s *Semaphore[K]) TryAcquire(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item := s.item(key)
+	if item.waiters.Len() == 0 && item.holders < s.capacityOf(key) {
+		item.holders++
+		return true
+	}
+	s.cleanup(key, item)
+	return false
+}
+
+// Acquire 阻塞获取许可, ctx 结束时退出排队并返回 ctx.Err()
+func (s *Semaphore[K]) Acquire(ctx context.Context, key K) error {
+	s.mu.Lock()
+	item := s.item(key)
+	if item.waiters.Len() == 0 && item.holders < s.capacityOf(key) {
+		item.holders++
+		s.mu.Unlock()
+		return nil
+	}
+	w := &semWaiter{ready: make(chan struct{})}
+	elem := item.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.granted {
+			// 取消的同时已获得许可, 归还给后面的等待者
+			item.holders--
+			s.grant(key, item)
+		} else {
+			item.waiters.Remove(elem)
+			// 排在队首的等待者退出后, 后面的等待者可能可以获得许可
+			s.grant(key, item)
+		}
+		s.cleanup(key, item)
+		return ctx.Err()
+	}
+}
+
+// Release 归还许可
+func (s *Semaphore[K]) Release(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[key]
+	if !ok || item.holders == 0 {
+		return
+	}
+	item.holders--
+	s.grant(key, item)
+	s.cleanup(key, item)
+}
+
+// grant 按排队顺序发放许可, 需持有 mu
+func (s *Semaphore[K]) grant(key K, item *semItem) {
+	capacity := s.capacityOf(key)
+	for item.holders < capacity && item.waiters.Len() > 0 {
+		front := item.waiters.Front()
+		item.waiters.Remove(front)
+		w := front.Value.(*semWaiter)
+		w.granted = true
+		item.holders++
+		close(w.ready)
+	}
+}
+
+// cleanup 无持有者与等待者时删除, 需持有 mu
+func (s *Semaphore[K]) cleanup(key K, item *semItem) {
+	if item.holders == 0 && item.waiters.Len() == 0 {
+		delete(s.items, key)
+	}
+}
+
+// Stats key 当前的持有与排队情况
+func (s *Semaphore[K]) Stats(key K) SemaphoreStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := SemaphoreStats{Capacity: s.capacityOf(key)}
+	if item, ok := s.items[key]; ok {
+		stats.Holders = item.holders
+		stats.Waiting = item.waiters.Len()
+	}
+	return stats
+}
+
+// AllStats 所有正在使用的 key 的持有与排队情况
+func (s *Semaphore[K]) AllStats() map[K]SemaphoreStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make(map[K]SemaphoreStats, len(s.items))
+	for key, item := range s.items {
+		all[key] = SemaphoreStats{
+			Capacity: s.capacityOf(key),
+			Holders:  item.holders,
+			Waiting:  item.waiters.Len(),
+		}
+	}
+	return all
+}
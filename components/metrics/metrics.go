@@ -0,0 +1,288 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultBuckets 默认耗时分布区间 秒
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default 默认注册表
+var Default = NewRegistry()
+
+// Sample 一个指标值, 直方图的 Buckets 为累计数量
+type Sample struct {
+	Labels  map[string]string
+	Value   float64
+	Buckets map[float64]uint64 // 仅直方图
+	Count   uint64             // 仅直方图
+}
+
+// Family 同名指标
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// CollectFunc 采集时调用, 用于连接池等需要实时读取的指标
+type CollectFunc func() []Family
+
+type metric interface {
+	family() Family
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.Mutex
+	metrics    map[string]metric
+	collectors []CollectFunc
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics:    make(map[string]metric),
+		collectors: make([]CollectFunc, 0),
+	}
+}
+
+// Counter 获取或创建计数器, 同名指标类型不一致时 panic
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return r.getOrCreate(name, func() metric {
+		return &Counter{vec: newVec(name, help, labels)}
+	}).(*Counter)
+}
+
+// Gauge 获取或创建仪表
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return r.getOrCreate(name, func() metric {
+		return &Gauge{vec: newVec(name, help, labels)}
+	}).(*Gauge)
+}
+
+// Histogram 获取或创建直方图, buckets 为空时使用 DefaultBuckets
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return r.getOrCreate(name, func() metric {
+		return &Histogram{vec: newVec(name, help, labels), buckets: sorted}
+	}).(*Histogram)
+}
+
+func (r *Registry) getOrCreate(name string, create func() metric) metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		created := create()
+		if fmt.Sprintf("%T", m) != fmt.Sprintf("%T", created) {
+			panic("metric already registered with another type: " + name)
+		}
+		return m
+	}
+	m := create()
+	r.metrics[name] = m
+	return m
+}
+
+// RegisterCollector 注册采集函数
+func (r *Registry) RegisterCollector(fn CollectFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, fn)
+}
+
+// Gather 收集所有指标, 按名称排序
+func (r *Registry) Gather() []Family {
+	r.mu.Lock()
+	families := make([]Family, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		families = append(families, m.family())
+	}
+	collectors := append([]CollectFunc{}, r.collectors...)
+	r.mu.Unlock()
+	for _, collect := range collectors {
+		families = append(families, collect()...)
+	}
+	sort.SliceStable(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+	return families
+}
+
+// vec 按标签值保存指标
+type vec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	values map[string]*value
+}
+
+type value struct {
+	labelValues []string
+	v           float64
+	count       uint64
+	buckets     []uint64
+}
+
+func newVec(name, help string, labels []string) *vec {
+	return &vec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]*value),
+	}
+}
+
+// with 需持有 mu
+func (v *vec) with(labelValues []string) *value {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	val, ok := v.values[key]
+	if !ok {
+		val = &value{labelValues: append([]string{}, labelValues...)}
+		v.values[key] = val
+	}
+	return val
+}
+
+func (v *vec) labelMap(labelValues []string) map[string]string {
+	labels := make(map[string]string, len(v.labels))
+	for i, name := range v.labels {
+		labels[name] = labelValues[i]
+	}
+	return labels
+}
+
+func (v *vec) collect(typ string, sample func(val *value) Sample) Family {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	f := Family{Name: v.name, Help: v.help, Type: typ, Samples: make([]Sample, 0, len(v.values))}
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f.Samples = append(f.Samples, sample(v.values[key]))
+	}
+	return f
+}
+
+// Counter 只增不减的计数
+type Counter struct {
+	vec *vec
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add v 不能为负数
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("counter cannot decrease: " + c.vec.name)
+	}
+	c.vec.mu.Lock()
+	defer c.vec.mu.Unlock()
+	c.vec.with(labelValues).v += v
+}
+
+func (c *Counter) family() Family {
+	return c.vec.collect(TypeCounter, func(val *value) Sample {
+		return Sample{Labels: c.vec.labelMap(val.labelValues), Value: val.v}
+	})
+}
+
+// Gauge 可增可减的值
+type Gauge struct {
+	vec *vec
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.vec.mu.Lock()
+	defer g.vec.mu.Unlock()
+	g.vec.with(labelValues).v = v
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.vec.mu.Lock()
+	defer g.vec.mu.Unlock()
+	g.vec.with(labelValues).v += v
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (g *Gauge) family() Family {
+	return g.vec.collect(TypeGauge, func(val *value) Sample {
+		return Sample{Labels: g.vec.labelMap(val.labelValues), Value: val.v}
+	})
+}
+
+// Histogram 数值分布
+type Histogram struct {
+	vec     *vec
+	buckets []float64
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+	val := h.vec.with(labelValues)
+	if val.buckets == nil {
+		val.buckets = make([]uint64, len(h.buckets))
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			val.buckets[i]++
+		}
+	}
+	val.v += v
+	val.count++
+}
+
+func (h *Histogram) family() Family {
+	return h.vec.collect(TypeHistogram, func(val *value) Sample {
+		buckets := make(map[float64]uint64, len(h.buckets))
+		for i, bound := range h.buckets {
+			buckets[bound] = val.buckets[i]
+		}
+		return Sample{Labels: h.vec.labelMap(val.labelValues), Value: val.v, Count: val.count, Buckets: buckets}
+	})
+}
+
+// NewCounter 在默认注册表中获取或创建计数器
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.Counter(name, help, labels...)
+}
+
+// NewGauge 在默认注册表中获取或创建仪表
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.Gauge(name, help, labels...)
+}
+
+// NewHistogram 在默认注册表中获取或创建直方图
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.Histogram(name, help, buckets, labels...)
+}
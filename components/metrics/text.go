@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteText 以 Prometheus 文本格式输出
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		if len(f.Samples) == 0 {
+			continue
+		}
+		bw.WriteString("# HELP " + f.Name + " " + escapeHelp(f.Help) + "\n")
+		bw.WriteString("# TYPE " + f.Name + " " + f.Type + "\n")
+		for _, s := range f.Samples {
+			if f.Type != TypeHistogram {
+				writeSample(bw, f.Name, s.Labels, "", "", s.Value)
+				continue
+			}
+			bounds := make([]float64, 0, len(s.Buckets))
+			for bound := range s.Buckets {
+				bounds = append(bounds, bound)
+			}
+			sort.Float64s(bounds)
+			for _, bound := range bounds {
+				writeSample(bw, f.Name+"_bucket", s.Labels, "le", formatFloat(bound), float64(s.Buckets[bound]))
+			}
+			writeSample(bw, f.Name+"_bucket", s.Labels, "le", "+Inf", float64(s.Count))
+			writeSample(bw, f.Name+"_sum", s.Labels, "", "", s.Value)
+			writeSample(bw, f.Name+"_count", s.Labels, "", "", float64(s.Count))
+		}
+	}
+	return bw.Flush()
+}
+
+func writeSample(w *bufio.Writer, name string, labels map[string]string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	if len(names) > 0 || extraName != "" {
+		pairs := make([]string, 0, len(names)+1)
+		for _, label := range names {
+			pairs = append(pairs, label+`="`+escapeLabel(labels[label])+`"`)
+		}
+		if extraName != "" {
+			pairs = append(pairs, extraName+`="`+extraValue+`"`)
+		}
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// Handler 输出指标的 http handler
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
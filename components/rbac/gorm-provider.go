@@ -0,0 +1,82 @@
+package rbac
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+// UserRole 用户角色
+type UserRole struct {
+	Id     int64  `gorm:"primaryKey"`
+	UserId string `gorm:"size:64;index:idx_user_role,unique"`
+	Role   string `gorm:"size:64;index:idx_user_role,unique"`
+}
+
+func (UserRole) TableName() string {
+	return "rbac_user_role"
+}
+
+// RolePermission 角色权限
+type RolePermission struct {
+	Id         int64  `gorm:"primaryKey"`
+	Role       string `gorm:"size:64;index:idx_role_permission,unique"`
+	Permission string `gorm:"size:128;index:idx_role_permission,unique"`
+}
+
+func (RolePermission) TableName() string {
+	return "rbac_role_permission"
+}
+
+// UserPermission 直接授予用户的权限
+type UserPermission struct {
+	Id         int64  `gorm:"primaryKey"`
+	UserId     string `gorm:"size:64;index:idx_user_permission,unique"`
+	Permission string `gorm:"size:128;index:idx_user_permission,unique"`
+}
+
+func (UserPermission) TableName() string {
+	return "rbac_user_permission"
+}
+
+// GormProvider 从数据库读取角色与权限
+type GormProvider struct {
+	db *gorm.DB
+}
+
+func NewGormProvider(db *gorm.DB) *GormProvider {
+	return &GormProvider{
+		db: db,
+	}
+}
+
+// Migrate 创建权限相关的表
+func (p *GormProvider) Migrate() error {
+	return p.db.AutoMigrate(&UserRole{}, &RolePermission{}, &UserPermission{})
+}
+
+func (p *GormProvider) Roles(ctx context.Context, userID string) ([]string, error) {
+	roles := make([]string, 0)
+	err := p.db.WithContext(ctx).Model(&UserRole{}).Where("user_id = ?", userID).Pluck("role", &roles).Error
+	return roles, err
+}
+
+func (p *GormProvider) Permissions(ctx context.Context, userID string) ([]string, error) {
+	roles, err := p.Roles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	perms := make([]string, 0)
+	err = p.db.WithContext(ctx).Model(&UserPermission{}).Where("user_id = ?", userID).Pluck("permission", &perms).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) > 0 {
+		rolePerms := make([]string, 0)
+		err = p.db.WithContext(ctx).Model(&RolePermission{}).Where("role IN ?", roles).Pluck("permission", &rolePerms).Error
+		if err != nil {
+			return nil, err
+		}
+		perms = append(perms, rolePerms...)
+	}
+	return perms, nil
+}
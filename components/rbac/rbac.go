@@ -0,0 +1,37 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+)
+
+// Provider 用户角色与权限来源
+type Provider interface {
+	// Roles 用户拥有的角色
+	Roles(ctx context.Context, userID string) ([]string, error)
+	// Permissions 用户拥有的全部权限, 包含通过角色获得的权限
+	Permissions(ctx context.Context, userID string) ([]string, error)
+}
+
+// Match 判断已授予的权限是否满足 required, 支持 * 及 order:* 形式的通配
+func Match(granted []string, required string) bool {
+	for _, perm := range granted {
+		if perm == required || perm == "*" {
+			return true
+		}
+		if strings.HasSuffix(perm, ":*") && strings.HasPrefix(required, perm[:len(perm)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAll 已授予的权限是否满足全部 required
+func MatchAll(granted []string, required []string) bool {
+	for _, perm := range required {
+		if !Match(granted, perm) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package reporters
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// FileReporter 每个事件以一行 json 追加到文件
+type FileReporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileReporter(path string) (*FileReporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileReporter{file: f}, nil
+}
+
+func (r *FileReporter) Report(ctx context.Context, event *Event) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(append(raw, '\n'))
+	return err
+}
+
+func (r *FileReporter) Close() error {
+	return r.file.Close()
+}
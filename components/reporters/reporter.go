@@ -0,0 +1,78 @@
+package reporters
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var errReportStatus = errors.New("上报失败")
+
+// Frame 调用栈中的一帧
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Event 异常事件
+type Event struct {
+	Time      time.Time         `json:"time"`
+	Message   string            `json:"message"`
+	Stack     string            `json:"stack"`
+	Frames    []Frame           `json:"frames,omitempty"` // 由内到外, 第一帧为 panic 发生的位置
+	RequestID string            `json:"request_id,omitempty"`
+	Role      string            `json:"role,omitempty"`
+	Group     string            `json:"group,omitempty"`
+	Action    string            `json:"action,omitempty"`
+	Method    string            `json:"method,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	UserID    string            `json:"user_id,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// Reporter 上报异常事件
+type Reporter interface {
+	Report(ctx context.Context, event *Event) error
+}
+
+// Callers 获取调用栈, skip 为跳过的层数, 0 表示调用 Callers 的函数
+// 在 recover 中调用时从 panic 发生的位置开始
+func Callers(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	list := make([]Frame, 0, n)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			// 丢弃 recover 处理函数的调用帧
+			list = list[:0]
+		} else if !strings.HasPrefix(frame.Function, "runtime.") {
+			list = append(list, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return list
+}
+
+// Multi 依次上报到多个 Reporter, 返回第一个错误
+func Multi(reporters ...Reporter) Reporter {
+	return multiReporter(reporters)
+}
+
+type multiReporter []Reporter
+
+func (m multiReporter) Report(ctx context.Context, event *Event) error {
+	var first error
+	for _, r := range m {
+		if err := r.Report(ctx, event); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
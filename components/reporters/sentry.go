@@ -0,0 +1,105 @@
+package reporters
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SentryReporter 通过 Sentry 的 store 接口上报, 兼容 Sentry 协议的服务均可使用
+type SentryReporter struct {
+	endpoint string
+	auth     string
+	env      string
+	client   *http.Client
+}
+
+// NewSentryReporter dsn 格式为 https://<key>@<host>/<project>, env 为上报的 environment
+func NewSentryReporter(dsn, env string, client *http.Client) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("sentry dsn 缺少 key")
+	}
+	project := strings.TrimPrefix(u.Path, "/")
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	if project == "" {
+		return nil, errors.New("sentry dsn 缺少 project")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SentryReporter{
+		endpoint: u.Scheme + "://" + u.Host + prefix + "/api/" + project + "/store/",
+		auth:     "Sentry sentry_version=7, sentry_client=catuan/1.0, sentry_key=" + u.User.Username(),
+		env:      env,
+		client:   client,
+	}, nil
+}
+
+func (r *SentryReporter) Report(ctx context.Context, event *Event) error {
+	// sentry 的调用栈由内到外排列, 最后一帧为出错位置
+	frames := make([]map[string]any, 0, len(event.Frames))
+	for i := len(event.Frames) - 1; i >= 0; i-- {
+		frame := event.Frames[i]
+		frames = append(frames, map[string]any{
+			"function": frame.Function,
+			"abs_path": frame.File,
+			"filename": frame.File,
+			"lineno":   frame.Line,
+			"in_app":   !strings.Contains(frame.File, "/pkg/mod/") && !strings.Contains(frame.File, "/src/runtime/"),
+		})
+	}
+	tags := map[string]string{
+		"role":   event.Role,
+		"group":  event.Group,
+		"action": event.Action,
+	}
+	for key, value := range event.Tags {
+		tags[key] = value
+	}
+	if event.RequestID != "" {
+		tags["request_id"] = event.RequestID
+	}
+	payload := map[string]any{
+		"event_id":    eventID(),
+		"timestamp":   event.Time.UTC().Format("2006-01-02T15:04:05Z"),
+		"level":       "error",
+		"platform":    "go",
+		"logger":      "catuan",
+		"environment": r.env,
+		"transaction": event.Role + "/" + event.Group + "/" + event.Action,
+		"message":     event.Message,
+		"tags":        tags,
+		"exception": map[string]any{
+			"values": []map[string]any{{
+				"type":       "panic",
+				"value":      event.Message,
+				"stacktrace": map[string]any{"frames": frames},
+			}},
+		},
+		"request": map[string]any{
+			"method": event.Method,
+			"url":    event.URL,
+		},
+	}
+	if event.UserID != "" {
+		payload["user"] = map[string]any{"id": event.UserID}
+	}
+	return postJSON(ctx, r.client, r.endpoint, http.Header{"X-Sentry-Auth": {r.auth}}, payload)
+}
+
+func eventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,50 @@
+package reporters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WebhookReporter 以 json POST 到指定地址
+type WebhookReporter struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookReporter(url string, client *http.Client) *WebhookReporter {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookReporter{url: url, client: client}
+}
+
+func (r *WebhookReporter) Report(ctx context.Context, event *Event) error {
+	return postJSON(ctx, r.client, r.url, nil, event)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body any) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: %s %d", errReportStatus, url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package sessions
+
+import (
+	"catuan/comm"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	data    Data
+	expires time.Time
+}
+
+type memoryUserSession struct {
+	id        string
+	createdAt time.Time
+}
+
+// MemoryStore 内存会话存储, 用于测试或单机调试
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]*memoryItem
+	users map[string][]memoryUserSession
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		items: make(map[string]*memoryItem),
+		users: make(map[string][]memoryUserSession),
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Data, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[id]
+	if !ok {
+		return nil, nil
+	}
+	if comm.Now().After(item.expires) {
+		delete(s.items, id)
+		return nil, nil
+	}
+	data := item.data
+	data.Values = make(map[string]string, len(item.data.Values))
+	for k, v := range item.data.Values {
+		data.Values[k] = v
+	}
+	return &data, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, id string, data *Data, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item := &memoryItem{data: *data, expires: comm.Now().Add(ttl)}
+	item.data.Values = make(map[string]string, len(data.Values))
+	for k, v := range data.Values {
+		item.data.Values[k] = v
+	}
+	s.items[id] = item
+	return nil
+}
+
+func (s *MemoryStore) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[id]
+	if !ok {
+		return nil
+	}
+	now := comm.Now()
+	// 已过期的会话不再续期
+	if now.After(item.expires) {
+		delete(s.items, id)
+		return nil
+	}
+	item.expires = now.Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, id)
+	return nil
+}
+
+func (s *MemoryStore) AddUserSession(ctx context.Context, userID string, id string, createdAt time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, item := range s.users[userID] {
+		if item.id == id {
+			s.users[userID][i].createdAt = createdAt
+			return nil
+		}
+	}
+	s.users[userID] = append(s.users[userID], memoryUserSession{id: id, createdAt: createdAt})
+	return nil
+}
+
+func (s *MemoryStore) UserSessions(ctx context.Context, userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := comm.Now()
+	valid := make([]memoryUserSession, 0, len(s.users[userID]))
+	for _, item := range s.users[userID] {
+		if data, ok := s.items[item.id]; ok && now.Before(data.expires) {
+			valid = append(valid, item)
+		}
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].createdAt.Before(valid[j].createdAt)
+	})
+	s.users[userID] = valid
+	ids := make([]string, 0, len(valid))
+	for _, item := range valid {
+		ids = append(ids, item.id)
+	}
+	return ids, nil
+}
+
+func (s *MemoryStore) RemoveUserSession(ctx context.Context, userID string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := s.users[userID]
+	for i, item := range sessions {
+		if item.id == id {
+			s.users[userID] = append(sessions[:i], sessions[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package sessions
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// RedisStore 会话数据以 json 保存, 用户会话索引保存在 zset 中
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "session:"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) dataKey(id string) string {
+	return s.prefix + id
+}
+
+func (s *RedisStore) userKey(userID string) string {
+	return s.prefix + "user:" + userID
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*Data, error) {
+	val, err := s.client.Get(ctx, s.dataKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := &Data{}
+	if err = json.Unmarshal(val, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, id string, data *Data, ttl time.Duration) error {
+	val, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.dataKey(id), val, ttl).Err()
+}
+
+func (s *RedisStore) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	return s.client.Expire(ctx, s.dataKey(id), ttl).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	return s.client.Del(ctx, s.dataKey(id)).Err()
+}
+
+func (s *RedisStore) AddUserSession(ctx context.Context, userID string, id string, createdAt time.Time, ttl time.Duration) error {
+	key := s.userKey(userID)
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, key, &redis.Z{Score: float64(createdAt.UnixNano()), Member: id})
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) UserSessions(ctx context.Context, userID string) ([]string, error) {
+	key := s.userKey(userID)
+	ids, err := s.client.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	// 剔除已过期的会话
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, pipe.Exists(ctx, s.dataKey(id)))
+	}
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	valid := make([]string, 0, len(ids))
+	expired := make([]interface{}, 0)
+	for i, cmd := range cmds {
+		if cmd.Val() > 0 {
+			valid = append(valid, ids[i])
+		} else {
+			expired = append(expired, ids[i])
+		}
+	}
+	if len(expired) > 0 {
+		s.client.ZRem(ctx, key, expired...)
+	}
+	return valid, nil
+}
+
+func (s *RedisStore) RemoveUserSession(ctx context.Context, userID string, id string) error {
+	return s.client.ZRem(ctx, s.userKey(userID), id).Err()
+}
@@ -0,0 +1,240 @@
+package sessions
+
+import (
+	"catuan/comm"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// Options 会话配置
+type Options struct {
+	Header     string        // 会话 ID 请求头, 默认 Session-Token
+	Expires    time.Duration // 过期时间, 每次访问后重新计算, 默认 2 小时
+	MaxPerUser int           // 单用户同时有效的会话数量, 超出时淘汰最早的会话, 0 为不限制
+}
+
+type Manager struct {
+	store Store
+	opt   Options
+}
+
+func NewManager(store Store, opt Options) *Manager {
+	if opt.Header == "" {
+		opt.Header = "Session-Token"
+	}
+	if opt.Expires <= 0 {
+		opt.Expires = time.Hour * 2
+	}
+	return &Manager{
+		store: store,
+		opt:   opt,
+	}
+}
+
+func (m *Manager) Header() string {
+	return m.opt.Header
+}
+
+func (m *Manager) Store() Store {
+	return m.store
+}
+
+// Load 读取会话并刷新过期时间, id 为空或会话不存在时创建新会话(请求结束且有数据写入时才保存)
+func (m *Manager) Load(ctx context.Context, id string) *Session {
+	if id != "" {
+		data, err := m.store.Get(ctx, id)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "读取会话异常",
+			}).Error(err.Error())
+		}
+		if data != nil {
+			if data.Values == nil {
+				data.Values = make(map[string]string)
+			}
+			return &Session{m: m, ctx: ctx, id: id, data: data, touched: true}
+		}
+	}
+	return &Session{
+		m:     m,
+		ctx:   ctx,
+		id:    newSessionID(),
+		data:  &Data{Values: make(map[string]string), CreatedAt: comm.Now().Unix()},
+		isNew: true,
+	}
+}
+
+func newSessionID() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+type Session struct {
+	m         *Manager
+	ctx       context.Context
+	id        string
+	data      *Data
+	isNew     bool // 本次请求创建或重新生成的会话
+	touched   bool // 需要刷新过期时间
+	dirty     bool
+	saved     bool
+	destroyed bool
+}
+
+func (s *Session) ID() string {
+	return s.id
+}
+
+// IsNew 会话 ID 是否在本次请求中生成
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+// Issued 本次请求生成并已保存的会话, 需要将 ID 返回给客户端
+func (s *Session) Issued() bool {
+	return s.isNew && s.saved && !s.destroyed
+}
+
+func (s *Session) UserID() string {
+	return s.data.UserID
+}
+
+func (s *Session) Get(key string) (string, bool) {
+	val, ok := s.data.Values[key]
+	return val, ok
+}
+
+func (s *Session) Set(key string, value string) {
+	s.data.Values[key] = value
+	s.dirty = true
+}
+
+// GetJSON 读取 json 格式的值
+func (s *Session) GetJSON(key string, v any) (bool, error) {
+	val, ok := s.data.Values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal([]byte(val), v)
+}
+
+// SetJSON 以 json 格式保存值
+func (s *Session) SetJSON(key string, v any) error {
+	val, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.Set(key, string(val))
+	return nil
+}
+
+func (s *Session) Delete(key string) {
+	if _, ok := s.data.Values[key]; ok {
+		delete(s.data.Values, key)
+		s.dirty = true
+	}
+}
+
+// Destroy 删除会话
+func (s *Session) Destroy() error {
+	s.destroyed = true
+	if s.data.UserID != "" {
+		if err := s.m.store.RemoveUserSession(s.ctx, s.data.UserID, s.id); err != nil {
+			return err
+		}
+	}
+	return s.m.store.Delete(s.ctx, s.id)
+}
+
+// Regenerate 更换会话 ID, 数据保留, 旧 ID 失效
+func (s *Session) Regenerate() error {
+	oldID := s.id
+	if !s.isNew {
+		if err := s.m.store.Delete(s.ctx, oldID); err != nil {
+			return err
+		}
+		if s.data.UserID != "" {
+			if err := s.m.store.RemoveUserSession(s.ctx, s.data.UserID, oldID); err != nil {
+				return err
+			}
+		}
+	}
+	s.id = newSessionID()
+	s.isNew = true
+	s.dirty = true
+	return nil
+}
+
+// Login 登录后绑定用户, 重新生成会话 ID 防止会话固定, 并淘汰超出数量的旧会话
+func (s *Session) Login(userID string) error {
+	if s.data.UserID != "" && !s.isNew {
+		if err := s.m.store.RemoveUserSession(s.ctx, s.data.UserID, s.id); err != nil {
+			return err
+		}
+	}
+	s.data.UserID = ""
+	if err := s.Regenerate(); err != nil {
+		return err
+	}
+	s.data.UserID = userID
+	s.data.CreatedAt = comm.Now().Unix()
+	// 先保存, 统计有效会话时包含当前会话
+	if err := s.Save(); err != nil {
+		return err
+	}
+	if s.m.opt.MaxPerUser <= 0 {
+		return nil
+	}
+	ids, err := s.m.store.UserSessions(s.ctx, userID)
+	if err != nil {
+		return err
+	}
+	excess := len(ids) - s.m.opt.MaxPerUser
+	for _, id := range ids {
+		if excess <= 0 {
+			break
+		}
+		if id == s.id {
+			continue
+		}
+		if err = s.m.store.Delete(s.ctx, id); err != nil {
+			return err
+		}
+		if err = s.m.store.RemoveUserSession(s.ctx, userID, id); err != nil {
+			return err
+		}
+		excess--
+	}
+	return nil
+}
+
+// Save 保存会话, 无数据变化时仅刷新过期时间
+func (s *Session) Save() error {
+	if s.destroyed || (!s.dirty && !s.touched) {
+		return nil
+	}
+	var err error
+	if s.dirty {
+		err = s.m.store.Save(s.ctx, s.id, s.data, s.m.opt.Expires)
+		s.saved = err == nil
+	} else {
+		err = s.m.store.Touch(s.ctx, s.id, s.m.opt.Expires)
+	}
+	if err != nil {
+		return err
+	}
+	s.dirty = false
+	s.touched = false
+	if s.data.UserID != "" {
+		// 同时刷新用户会话索引的过期时间
+		return s.m.store.AddUserSession(s.ctx, s.data.UserID, s.id, time.Unix(s.data.CreatedAt, 0), s.m.opt.Expires)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package sessions
+
+import (
+	"context"
+	"time"
+)
+
+// Data 会话数据
+type Data struct {
+	UserID    string            `json:"uid,omitempty"`
+	Values    map[string]string `json:"values"`
+	CreatedAt int64             `json:"created_at"`
+}
+
+// Store 会话存储
+type Store interface {
+	// Get 读取会话, 不存在时返回 nil, nil
+	Get(ctx context.Context, id string) (*Data, error)
+	Save(ctx context.Context, id string, data *Data, ttl time.Duration) error
+	// Touch 刷新会话过期时间
+	Touch(ctx context.Context, id string, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+
+	// AddUserSession 记录用户的会话并刷新索引过期时间, 重复添加时更新创建时间, 用于限制同时在线的会话数量
+	AddUserSession(ctx context.Context, userID string, id string, createdAt time.Time, ttl time.Duration) error
+	// UserSessions 用户有效的会话, 按创建时间从早到晚排序
+	UserSessions(ctx context.Context, userID string) ([]string, error)
+	RemoveUserSession(ctx context.Context, userID string, id string) error
+}
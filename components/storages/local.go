@@ -0,0 +1,168 @@
+package storages
+
+import (
+	"catuan/comm"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStorage 本地文件存储, 签名地址由 Handler 校验后提供下载
+type LocalStorage struct {
+	root    string
+	baseURL string
+	secret  string
+}
+
+// NewLocalStorage root 为存储目录, baseURL 为 Handler 挂载的地址, secret 用于签名下载地址
+func NewLocalStorage(root, baseURL, secret string) (*LocalStorage, error) {
+	if secret == "" {
+		return nil, errors.New("本地存储签名密钥不能为空")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{
+		root:    root,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  secret,
+	}, nil
+}
+
+func (s *LocalStorage) path(key string) (string, string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return key, filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put 先写入临时文件再重命名, 避免读取到写入中的文件
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	key, name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return nil, err
+	}
+	return s.Stat(ctx, key)
+}
+
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadSeekCloser, *Object, error) {
+	obj, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, name, _ := s.path(key)
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, obj, nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*Object, error) {
+	key, name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: contentType,
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	_, name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// SignURL 返回 baseURL/key?expires=&sign=
+func (s *LocalStorage) SignURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	expiresAt := comm.Now().Add(expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	query.Set("sign", Sign(s.secret, key, expiresAt))
+	return s.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Handler 校验签名后提供下载, 支持 Range, 需配合 http.StripPrefix 去除挂载路径
+// 始终以附件形式返回并禁止浏览器猜测类型, 避免上传的文件在当前域名下被当作页面执行
+func (s *LocalStorage) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := CleanKey(r.URL.Path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if err = VerifySign(s.secret, key, expires, r.URL.Query().Get("sign")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		f, obj, err := s.Open(r.Context(), key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		header := w.Header()
+		header.Set("Content-Type", obj.ContentType)
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(path.Base(key)))
+		http.ServeContent(w, r, path.Base(key), obj.ModTime, f)
+	})
+}
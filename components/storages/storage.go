@@ -0,0 +1,69 @@
+package storages
+
+import (
+	"catuan/comm"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("文件不存在")
+	ErrInvalidKey  = errors.New("文件路径不合法")
+	ErrSignInvalid = errors.New("签名无效或已过期")
+)
+
+// Object 文件信息
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	ETag        string
+}
+
+// Storage 文件存储, key 为 / 分隔的相对路径
+type Storage interface {
+	// Put 保存文件, size 未知时传 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error)
+	// Open 打开文件, 返回的 reader 支持 Seek, 用于分段下载
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, *Object, error)
+	Stat(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+	// SignURL 生成有效期为 expires 的下载地址
+	SignURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// CleanKey 规范化 key, 不允许跳出根目录
+func CleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, "\\", "/")), "/")
+	if key == "" || key == "." {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
+
+// Sign 对 key 和过期时间签名
+func Sign(secret, key string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySign 校验 Sign 生成的签名及过期时间
+func VerifySign(secret, key string, expires int64, sign string) error {
+	if comm.Now().Unix() > expires {
+		return ErrSignInvalid
+	}
+	if !hmac.Equal([]byte(Sign(secret, key, expires)), []byte(sign)) {
+		return ErrSignInvalid
+	}
+	return nil
+}
@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"github.com/sirupsen/logrus"
 	"io"
+	"net/http"
 )
 
 type MiniApp struct {
-	appid  string
-	secret string
+	appid      string
+	secret     string
+	httpClient *http.Client
 }
 
 func NewMiniApp(appid, secret string) *MiniApp {
@@ -20,6 +22,26 @@ func NewMiniApp(appid, secret string) *MiniApp {
 	}
 }
 
+// NewMiniAppWithClient 使用指定的 http client 请求微信接口
+func NewMiniAppWithClient(appid, secret string, client *http.Client) *MiniApp {
+	return &MiniApp{
+		appid:      appid,
+		secret:     secret,
+		httpClient: client,
+	}
+}
+
+func (m *MiniApp) SetHttpClient(client *http.Client) {
+	m.httpClient = client
+}
+
+func (m *MiniApp) client() *http.Client {
+	if m.httpClient != nil {
+		return m.httpClient
+	}
+	return http_client.HttpClient
+}
+
 type RespCode2SessionInfo struct {
 	Openid     string `json:"openid"`
 	SessionKey string `json:"session_key"`
@@ -30,7 +52,7 @@ type RespCode2SessionInfo struct {
 
 func (m *MiniApp) Code2Session(code string, result *RespCode2SessionInfo) error {
 	reqUrl := "https://api.weixin.qq.com/sns/jscode2session?appid=" + m.appid + "&secret=" + m.secret + "&js_code=" + code + "&grant_type=authorization_code"
-	resp, err := http_client.HttpClient.Get(reqUrl)
+	resp, err := m.client().Get(reqUrl)
 	if err != nil {
 		return err
 	}
@@ -59,7 +81,7 @@ type RespAccessTokenInfo struct {
 
 func (m *MiniApp) GetAccessToken(result *RespAccessTokenInfo) error {
 	reqUrl := "https://api.weixin.qq.com/cgi-bin/token?grant_type=client_credential&appid=" + m.appid + "&secret=" + m.secret
-	resp, err := http_client.HttpClient.Get(reqUrl)
+	resp, err := m.client().Get(reqUrl)
 	if err != nil {
 		return err
 	}
@@ -95,7 +117,7 @@ type PhoneInfo struct {
 func (m *MiniApp) GetPhone(accessToken string, code string, result *RespUserPhoneInfo) error {
 	reqUrl := "https://api.weixin.qq.com/wxa/business/getuserphonenumber?access_token=" + accessToken
 	codeData := `{"code":"` + code + `"}`
-	resp, err := http_client.HttpClient.Post(reqUrl, "application/json", bytes.NewReader([]byte(codeData)))
+	resp, err := m.client().Post(reqUrl, "application/json", bytes.NewReader([]byte(codeData)))
 	if err != nil {
 		return err
 	}
@@ -136,7 +158,7 @@ func (m *MiniApp) GetQRCode(accessToken string, option QRCodeOptionInfo) ([]byte
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http_client.HttpClient.Post(reqUrl, "application/json", bytes.NewReader(optionData))
+	resp, err := m.client().Post(reqUrl, "application/json", bytes.NewReader(optionData))
 	if err != nil {
 		return nil, err
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/wechatpay-apiv3/wechatpay-go/core/option"
 	"github.com/wechatpay-apiv3/wechatpay-go/utils"
 	"io"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
@@ -29,6 +30,7 @@ type WechatPay struct {
 	CertFile            string
 	MchCertSerialNumber string
 	Client              *core.Client
+	HttpClient          *http.Client // 为空时 v3 接口使用 SDK 默认 client, v2 接口使用 http_client.HttpClient
 	rsaKey              *rsa.PrivateKey
 }
 
@@ -90,6 +92,9 @@ func (w *WechatPay) GetClient() (*core.Client, error) {
 		opts := []core.ClientOption{
 			option.WithWechatPayAutoAuthCipher(w.MchId, w.MchCertSerialNumber, mchPrivateKey, w.MchKey),
 		}
+		if w.HttpClient != nil {
+			opts = append(opts, option.WithHTTPClient(w.HttpClient))
+		}
 		client, err := core.NewClient(context.Background(), opts...)
 		if err != nil {
 			return nil, errors.New("初始化微信支付客户端失败")
@@ -99,6 +104,13 @@ func (w *WechatPay) GetClient() (*core.Client, error) {
 	return w.Client, nil
 }
 
+func (w *WechatPay) httpClient() *http.Client {
+	if w.HttpClient != nil {
+		return w.HttpClient
+	}
+	return http_client.HttpClient
+}
+
 func (w *WechatPay) loadPrivateKey() (*rsa.PrivateKey, error) {
 	mchPrivateKey, err := utils.LoadPrivateKeyWithPath(w.CertKeyFile)
 	if err != nil {
@@ -543,7 +555,7 @@ func (w *WechatPay) ScancodePayV2(payInfo ScanPayerInfo) (*RespScancodePayV2, er
 		return nil, err
 	}
 	reqUrl := "https://api.mch.weixin.qq.com/pay/micropay"
-	resp, err := http_client.HttpClient.Post(reqUrl, "application/xml", bytes.NewReader(reqBytes))
+	resp, err := w.httpClient().Post(reqUrl, "application/xml", bytes.NewReader(reqBytes))
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"tip": "扫码请求支付请求失败",
@@ -0,0 +1,233 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Exporter 导出结束的 span
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// StdoutExporter 每个 span 输出一行 json, 用于本地调试
+type StdoutExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdoutExporter w 为 nil 时输出到标准输出
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &StdoutExporter{w: w}
+}
+
+type stdoutSpan struct {
+	TraceID       string         `json:"traceId"`
+	SpanID        string         `json:"spanId"`
+	ParentSpanID  string         `json:"parentSpanId,omitempty"`
+	Service       string         `json:"service"`
+	Name          string         `json:"name"`
+	Kind          string         `json:"kind"`
+	Start         time.Time      `json:"start"`
+	DurationMs    float64        `json:"durationMs"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+	Status        string         `json:"status,omitempty"`
+	StatusMessage string         `json:"statusMessage,omitempty"`
+}
+
+func (e *StdoutExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		item := stdoutSpan{
+			TraceID:       s.TraceID.String(),
+			SpanID:        s.SpanID.String(),
+			Service:       s.Service,
+			Name:          s.Name,
+			Kind:          s.Kind.String(),
+			Start:         s.Start,
+			DurationMs:    float64(s.End.Sub(s.Start).Microseconds()) / 1000,
+			Attributes:    s.Attributes,
+			StatusMessage: s.StatusMessage,
+		}
+		if s.ParentSpanID.IsValid() {
+			item.ParentSpanID = s.ParentSpanID.String()
+		}
+		switch s.StatusCode {
+		case StatusOK:
+			item.Status = "ok"
+		case StatusError:
+			item.Status = "error"
+		}
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OTLPExporter 以 OTLP/HTTP json 格式发送到 collector
+type OTLPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewOTLPExporter endpoint 未指定路径时使用 /v1/traces, client 为 nil 时使用 10 秒超时的默认 client
+func NewOTLPExporter(endpoint string, headers map[string]string, client *http.Client) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("otlp endpoint must be http or https: " + endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: time.Second * 10}
+	}
+	return &OTLPExporter{endpoint: u.String(), headers: headers, client: client}, nil
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp export: %s %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            map[string]any `json:"status,omitempty"`
+}
+
+// otlpRequest 按服务名分组, 结构对应 ExportTraceServiceRequest
+func otlpRequest(spans []SpanData) map[string]any {
+	services := make([]string, 0)
+	grouped := make(map[string][]otlpSpan)
+	for _, s := range spans {
+		if _, ok := grouped[s.Service]; !ok {
+			services = append(services, s.Service)
+		}
+		item := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+		}
+		if s.ParentSpanID.IsValid() {
+			item.ParentSpanID = s.ParentSpanID.String()
+		}
+		if s.StatusCode != StatusUnset {
+			item.Status = map[string]any{"code": int(s.StatusCode), "message": s.StatusMessage}
+		}
+		grouped[s.Service] = append(grouped[s.Service], item)
+	}
+	resourceSpans := make([]map[string]any, 0, len(services))
+	for _, service := range services {
+		resourceSpans = append(resourceSpans, map[string]any{
+			"resource": map[string]any{
+				"attributes": otlpAttributes(map[string]any{"service.name": service}),
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "catuan"},
+				"spans": grouped[service],
+			}},
+		})
+	}
+	return map[string]any{"resourceSpans": resourceSpans}
+}
+
+func otlpAttributes(attrs map[string]any) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpValue(attrs[k])})
+	}
+	return kvs
+}
+
+func otlpValue(v any) map[string]any {
+	switch val := v.(type) {
+	case string:
+		return map[string]any{"stringValue": val}
+	case bool:
+		return map[string]any{"boolValue": val}
+	case int:
+		return map[string]any{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int32:
+		return map[string]any{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(val, 10)}
+	case uint32:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(val), 10)}
+	case uint64:
+		return map[string]any{"intValue": strconv.FormatUint(val, 10)}
+	case float32:
+		return map[string]any{"doubleValue": float64(val)}
+	case float64:
+		return map[string]any{"doubleValue": val}
+	}
+	return map[string]any{"stringValue": fmt.Sprint(v)}
+}
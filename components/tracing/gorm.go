@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"errors"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// GormPlugin 为 sql 操作创建子 span, 需通过 db.WithContext 传入包含 span 的 ctx
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "catuan:tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("tracing:before_create", gormBefore("gorm.create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", gormAfter),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", gormBefore("gorm.query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", gormAfter),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", gormBefore("gorm.update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", gormAfter),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", gormBefore("gorm.delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", gormAfter),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", gormBefore("gorm.row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", gormAfter),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", gormBefore("gorm.raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", gormAfter),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func gormBefore(name string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		_, span := StartSpan(db.Statement.Context, name, WithKind(SpanKindClient))
+		if span == nil {
+			return
+		}
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+// gormAfter 记录不含参数值的 sql, 避免敏感数据进入 trace
+func gormAfter(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(*Span)
+	if !ok {
+		return
+	}
+	if db.Dialector != nil {
+		span.SetAttribute("db.system", db.Dialector.Name())
+	}
+	span.SetAttribute("db.statement", db.Statement.SQL.String())
+	if db.Statement.Table != "" {
+		span.SetAttribute("db.sql.table", db.Statement.Table)
+	}
+	span.SetAttribute("db.rows_affected", db.RowsAffected)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.SetError(db.Error)
+	}
+	span.End()
+}
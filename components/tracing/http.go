@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Transport 为请求创建 client span 并注入 traceparent, 请求 ctx 中没有 span 时只透传上游 trace context
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx, span := StartSpan(req.Context(), "HTTP "+req.Method, WithKind(SpanKindClient))
+	if span == nil {
+		if _, ok := SpanContextFromContext(ctx); !ok {
+			return base.RoundTrip(req)
+		}
+	}
+	// RoundTripper 不能修改原请求
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+	if span == nil {
+		return base.RoundTrip(req)
+	}
+	defer span.End()
+	// 不记录 query, 其中可能包含密钥
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+	span.SetAttribute("net.peer.name", req.URL.Hostname())
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return resp, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.SetStatus(StatusError, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+)
+
+type redisSpanKey struct{}
+
+// RedisHook 为 redis 命令创建子 span, 只记录命令名不记录参数
+type RedisHook struct{}
+
+var _ redis.Hook = RedisHook{}
+
+func (RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startRedisSpan(ctx, "redis "+cmd.Name(), map[string]any{"db.operation": cmd.Name()}), nil
+}
+
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisSpan(ctx, cmd.Err())
+	return nil
+}
+
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return startRedisSpan(ctx, "redis pipeline", map[string]any{"db.redis.commands": len(cmds)}), nil
+}
+
+func (RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			err = cmdErr
+			break
+		}
+	}
+	endRedisSpan(ctx, err)
+	return nil
+}
+
+func startRedisSpan(ctx context.Context, name string, attrs map[string]any) context.Context {
+	attrs["db.system"] = "redis"
+	spanCtx, span := StartSpan(ctx, name, WithKind(SpanKindClient), WithAttributes(attrs))
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(spanCtx, redisSpanKey{}, span)
+}
+
+func endRedisSpan(ctx context.Context, err error) {
+	span, ok := ctx.Value(redisSpanKey{}).(*Span)
+	if !ok {
+		return
+	}
+	if err != nil && err != redis.Nil {
+		span.SetError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type SpanKind int
+
+// 与 OTLP 的取值一致
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	}
+	return "internal"
+}
+
+type StatusCode int
+
+// 与 OTLP 的取值一致
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// SpanData 结束后交给 exporter 的 span 数据
+type SpanData struct {
+	Service       string
+	Name          string
+	Kind          SpanKind
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentSpanID  SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]any
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+// Span 一次操作, 方法均可在 nil 上调用
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	sc     SpanContext
+	ended  bool
+}
+
+type SpanOption func(s *Span)
+
+func WithKind(kind SpanKind) SpanOption {
+	return func(s *Span) {
+		s.data.Kind = kind
+	}
+}
+
+func WithAttributes(attrs map[string]any) SpanOption {
+	return func(s *Span) {
+		for k, v := range attrs {
+			s.data.Attributes[k] = v
+		}
+	}
+}
+
+// StartSpan 以 ctx 中的 span 为父级创建子 span, ctx 中没有 span 时返回 nil
+func StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, opts...)
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.sc.TraceID.String()
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.StatusCode = code
+	s.data.StatusMessage = message
+}
+
+// SetError 标记为失败, err 为 nil 时忽略
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End 结束 span 并交给 exporter, 重复调用无效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if s.sc.Sampled() {
+		s.tracer.enqueue(data)
+	}
+}
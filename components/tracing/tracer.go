@@ -0,0 +1,215 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options tracer 配置
+type Options struct {
+	Service       string
+	Exporters     []Exporter
+	SampleRatio   float64       // 根 span 采样比例, 0 或 >= 1 时全部采样, 有上游时跟随上游
+	BatchSize     int           // 每批导出数量, 默认 256
+	FlushInterval time.Duration // 导出间隔, 默认 5 秒
+	QueueSize     int           // 待导出队列长度, 默认 2048, 满时丢弃
+}
+
+// Tracer 创建 span, 结束的 span 批量异步导出
+type Tracer struct {
+	opt      Options
+	queue    chan SpanData
+	flushReq chan chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	dropped  uint64
+}
+
+func NewTracer(opt Options) *Tracer {
+	if opt.Service == "" {
+		opt.Service = "catuan"
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 256
+	}
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = time.Second * 5
+	}
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = 2048
+	}
+	t := &Tracer{
+		opt:      opt,
+		flushReq: make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if len(opt.Exporters) > 0 {
+		t.queue = make(chan SpanData, opt.QueueSize)
+		go t.loop()
+	} else {
+		close(t.done)
+	}
+	return t
+}
+
+func (t *Tracer) Service() string {
+	return t.opt.Service
+}
+
+// Start 创建 span, 父级为 ctx 中的 span 或上游 trace context, 返回包含新 span 的 ctx
+func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Service:    t.opt.Service,
+			Name:       name,
+			Kind:       SpanKindInternal,
+			Start:      time.Now(),
+			Attributes: make(map[string]any),
+		},
+	}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		span.sc = SpanContext{TraceID: parent.TraceID, Flags: parent.Flags, TraceState: parent.TraceState}
+		span.data.ParentSpanID = parent.SpanID
+	} else {
+		span.sc = SpanContext{TraceID: newTraceID()}
+		if t.sample(span.sc.TraceID) {
+			span.sc.Flags = FlagSampled
+		}
+	}
+	span.sc.SpanID = newSpanID()
+	span.data.TraceID = span.sc.TraceID
+	span.data.SpanID = span.sc.SpanID
+	for _, opt := range opts {
+		opt(span)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample 按 trace id 低 8 字节决定是否采样, 同一 trace 结果一致
+func (t *Tracer) sample(id TraceID) bool {
+	ratio := t.opt.SampleRatio
+	if ratio <= 0 || ratio >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(id[8:])>>1) < ratio*float64(uint64(1)<<63)
+}
+
+// Dropped 队列已满被丢弃的 span 数量
+func (t *Tracer) Dropped() uint64 {
+	return atomic.LoadUint64(&t.dropped)
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	if t.queue == nil {
+		return
+	}
+	select {
+	case <-t.stop:
+		return
+	default:
+	}
+	select {
+	case t.queue <- data:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// Flush 立即导出队列中的 span
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t.queue == nil {
+		return nil
+	}
+	reply := make(chan struct{})
+	select {
+	case t.flushReq <- reply:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown 导出剩余 span 后停止, 之后结束的 span 不再导出
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Tracer) loop() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.opt.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]SpanData, 0, t.opt.BatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		t.export(batch)
+		batch = make([]SpanData, 0, t.opt.BatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case data := <-t.queue:
+				batch = append(batch, data)
+				if len(batch) >= t.opt.BatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.opt.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case reply := <-t.flushReq:
+			drain()
+			close(reply)
+		case <-t.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (t *Tracer) export(batch []SpanData) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	for _, exporter := range t.opt.Exporters {
+		if err := exporter.Export(ctx, batch); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip":   "导出 trace 失败",
+				"spans": len(batch),
+			}).Warn(err.Error())
+		}
+	}
+}
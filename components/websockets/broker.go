@@ -0,0 +1,76 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	TargetUser  = "user"
+	TargetTopic = "topic"
+	TargetAll   = "all"
+)
+
+// Envelope 实例间转发的消息
+type Envelope struct {
+	Origin string `json:"origin"` // 发送消息的 Hub, 用于忽略自己发出的消息
+	Target string `json:"target"` // user/topic/all
+	Key    string `json:"key,omitempty"`
+	Data   []byte `json:"data"`
+}
+
+// Broker 在多个实例之间转发消息
+type Broker interface {
+	Publish(ctx context.Context, env *Envelope) error
+	// Subscribe 阻塞接收消息, ctx 结束后返回
+	Subscribe(ctx context.Context, handler func(env *Envelope)) error
+}
+
+// RedisBroker 基于 redis pub/sub 转发
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+}
+
+func NewRedisBroker(client *redis.Client, channel string) *RedisBroker {
+	if channel == "" {
+		channel = "websocket:broadcast"
+	}
+	return &RedisBroker{
+		client:  client,
+		channel: channel,
+	}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, env *Envelope) error {
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, raw).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, handler func(env *Envelope)) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			env := &Envelope{}
+			if err := json.Unmarshal([]byte(msg.Payload), env); err != nil {
+				continue
+			}
+			handler(env)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package websockets
+
+import (
+	"errors"
+	"golang.org/x/net/websocket"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrConnClosed 连接已关闭
+var ErrConnClosed = errors.New("websocket connection closed")
+
+// ErrSendBufferFull 发送缓冲已满, 连接会被关闭
+var ErrSendBufferFull = errors.New("websocket send buffer full")
+
+// pingCodec 发送 ping 帧, 与其他写入共用连接的写锁
+var pingCodec = websocket.Codec{
+	Marshal: func(v any) ([]byte, byte, error) {
+		return nil, websocket.PingFrame, nil
+	},
+}
+
+// MessageFunc 收到客户端消息时调用
+type MessageFunc func(conn *Conn, msg []byte)
+
+// Conn 单个 websocket 连接, 写入由独立 goroutine 完成
+type Conn struct {
+	id     string
+	userID string
+	ws     *websocket.Conn
+	hub    *Hub
+
+	send      chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+	onMessage MessageFunc
+
+	mu     sync.Mutex
+	topics map[string]bool
+}
+
+func (c *Conn) ID() string {
+	return c.id
+}
+
+// UserID 连接所属用户, 未登录时为空
+func (c *Conn) UserID() string {
+	return c.userID
+}
+
+// Raw 底层连接
+func (c *Conn) Raw() *websocket.Conn {
+	return c.ws
+}
+
+// OnMessage 设置消息处理函数, 需在 Serve 开始前设置
+func (c *Conn) OnMessage(fn MessageFunc) {
+	c.onMessage = fn
+}
+
+// Send 发送消息, 字符串与 []byte 原样发送, 其他类型编码为 json, 缓冲已满时关闭连接
+func (c *Conn) Send(data any) error {
+	msg, err := encodeMessage(data)
+	if err != nil {
+		return err
+	}
+	return c.sendRaw(msg)
+}
+
+func (c *Conn) sendRaw(msg []byte) error {
+	select {
+	case <-c.closed:
+		return ErrConnClosed
+	default:
+	}
+	select {
+	case c.send <- msg:
+		return nil
+	case <-c.closed:
+		return ErrConnClosed
+	default:
+		c.Close()
+		return ErrSendBufferFull
+	}
+}
+
+// Subscribe 订阅主题, 接收 Hub.Publish 发送的消息
+func (c *Conn) Subscribe(topics ...string) {
+	c.mu.Lock()
+	for _, topic := range topics {
+		c.topics[topic] = true
+	}
+	c.mu.Unlock()
+	c.hub.subscribe(c, topics...)
+}
+
+func (c *Conn) Unsubscribe(topics ...string) {
+	c.mu.Lock()
+	for _, topic := range topics {
+		delete(c.topics, topic)
+	}
+	c.mu.Unlock()
+	c.hub.unsubscribe(c, topics...)
+}
+
+// Topics 已订阅的主题
+func (c *Conn) Topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Close 关闭连接, 可重复调用
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.ws.Close()
+	})
+}
+
+// Done 连接关闭后结束
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// serve 读取客户端消息直到连接关闭, 客户端发送 ping 时回复 pong
+func (c *Conn) serve() {
+	go c.writeLoop()
+	defer c.Close()
+	for {
+		msg, err := c.receive()
+		if err != nil {
+			return
+		}
+		if string(msg) == "ping" {
+			_ = c.sendRaw([]byte("pong"))
+			continue
+		}
+		if c.onMessage != nil {
+			c.onMessage(c, msg)
+		}
+	}
+}
+
+// receive 读取下一条消息, 每收到一帧(包括回复服务端 ping 的 pong 帧)都重新计算读取超时
+func (c *Conn) receive() ([]byte, error) {
+	opt := c.hub.opt
+	for {
+		if opt.ReadTimeout > 0 {
+			c.ws.SetReadDeadline(time.Now().Add(opt.ReadTimeout))
+		}
+		frame, err := c.ws.NewFrameReader()
+		if err != nil {
+			return nil, err
+		}
+		// 控制帧由 HandleFrame 处理后返回 nil, 关闭帧返回 io.EOF
+		frame, err = c.ws.HandleFrame(frame)
+		if err != nil {
+			return nil, err
+		}
+		if frame == nil {
+			continue
+		}
+		maxPayloadBytes := c.ws.MaxPayloadBytes
+		if maxPayloadBytes == 0 {
+			maxPayloadBytes = websocket.DefaultMaxPayloadBytes
+		}
+		msg, err := io.ReadAll(io.LimitReader(frame, int64(maxPayloadBytes)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) > maxPayloadBytes {
+			return nil, websocket.ErrFrameTooLarge
+		}
+		return msg, nil
+	}
+}
+
+// writeLoop 唯一的写入 goroutine, 定时发送 ping 帧检测连接
+func (c *Conn) writeLoop() {
+	defer c.Close()
+	opt := c.hub.opt
+	ticker := time.NewTicker(opt.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case msg := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(opt.WriteTimeout))
+			if err := websocket.Message.Send(c.ws, string(msg)); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.ws.SetWriteDeadline(time.Now().Add(opt.WriteTimeout))
+			if err := pingCodec.Send(c.ws, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package websockets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Options Hub 参数
+type Options struct {
+	PingInterval time.Duration // 发送 ping 帧的间隔, 默认 30 秒
+	ReadTimeout  time.Duration // 超过该时间未收到客户端的任何帧(包括 pong)时断开, 需大于 PingInterval, 0 为不限制
+	WriteTimeout time.Duration // 单条消息写入超时, 默认 10 秒
+	SendBuffer   int           // 每个连接的发送缓冲, 默认 64
+	Broker       Broker        // 为空时仅在本实例内发送
+	// CheckOrigin 校验 Origin, 默认允许无 Origin 或与 Host 相同的请求
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Hub 管理 websocket 连接, 支持按用户/主题发送
+type Hub struct {
+	id  string
+	opt Options
+
+	mu     sync.RWMutex
+	conns  map[*Conn]bool
+	users  map[string]map[*Conn]bool
+	topics map[string]map[*Conn]bool
+
+	cancel context.CancelFunc
+}
+
+func NewHub(opt Options) *Hub {
+	if opt.PingInterval <= 0 {
+		opt.PingInterval = time.Second * 30
+	}
+	if opt.WriteTimeout <= 0 {
+		opt.WriteTimeout = time.Second * 10
+	}
+	if opt.SendBuffer <= 0 {
+		opt.SendBuffer = 64
+	}
+	if opt.CheckOrigin == nil {
+		opt.CheckOrigin = sameOrigin
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Hub{
+		id:     randomID(),
+		opt:    opt,
+		conns:  make(map[*Conn]bool),
+		users:  make(map[string]map[*Conn]bool),
+		topics: make(map[string]map[*Conn]bool),
+		cancel: cancel,
+	}
+	if opt.Broker != nil {
+		go h.receive(ctx)
+	}
+	return h
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Upgrade 完成握手并注册连接, 调用 fn 后读取消息直到连接关闭
+func (h *Hub) Upgrade(w http.ResponseWriter, r *http.Request, userID string, fn func(conn *Conn)) {
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			if !h.opt.CheckOrigin(req) {
+				return websocket.ErrBadWebSocketOrigin
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			conn := &Conn{
+				id:     randomID(),
+				userID: userID,
+				ws:     ws,
+				hub:    h,
+				send:   make(chan []byte, h.opt.SendBuffer),
+				closed: make(chan struct{}),
+				topics: make(map[string]bool),
+			}
+			h.register(conn)
+			defer h.unregister(conn)
+			if fn != nil {
+				fn(conn)
+			}
+			conn.serve()
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+func (h *Hub) register(conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[conn] = true
+	if conn.userID != "" {
+		if h.users[conn.userID] == nil {
+			h.users[conn.userID] = make(map[*Conn]bool)
+		}
+		h.users[conn.userID][conn] = true
+	}
+}
+
+func (h *Hub) unregister(conn *Conn) {
+	conn.Close()
+	topics := conn.Topics()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, conn)
+	removeConn(h.users, conn.userID, conn)
+	for _, topic := range topics {
+		removeConn(h.topics, topic, conn)
+	}
+}
+
+func removeConn(index map[string]map[*Conn]bool, key string, conn *Conn) {
+	if set, ok := index[key]; ok {
+		delete(set, conn)
+		if len(set) == 0 {
+			delete(index, key)
+		}
+	}
+}
+
+func (h *Hub) subscribe(conn *Conn, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.conns[conn] {
+		return
+	}
+	for _, topic := range topics {
+		if h.topics[topic] == nil {
+			h.topics[topic] = make(map[*Conn]bool)
+		}
+		h.topics[topic][conn] = true
+	}
+}
+
+func (h *Hub) unsubscribe(conn *Conn, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		removeConn(h.topics, topic, conn)
+	}
+}
+
+// SendToUser 发送给用户的所有连接, 包括其他实例上的连接
+func (h *Hub) SendToUser(userID string, data any) error {
+	return h.dispatch(TargetUser, userID, data)
+}
+
+// Publish 发送给订阅了主题的所有连接
+func (h *Hub) Publish(topic string, data any) error {
+	return h.dispatch(TargetTopic, topic, data)
+}
+
+// Broadcast 发送给所有连接
+func (h *Hub) Broadcast(data any) error {
+	return h.dispatch(TargetAll, "", data)
+}
+
+func (h *Hub) dispatch(target, key string, data any) error {
+	msg, err := encodeMessage(data)
+	if err != nil {
+		return err
+	}
+	h.deliver(target, key, msg)
+	if h.opt.Broker == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.opt.WriteTimeout)
+	defer cancel()
+	return h.opt.Broker.Publish(ctx, &Envelope{Origin: h.id, Target: target, Key: key, Data: msg})
+}
+
+// deliver 发送给本实例上的连接
+func (h *Hub) deliver(target, key string, msg []byte) {
+	h.mu.RLock()
+	var set map[*Conn]bool
+	switch target {
+	case TargetUser:
+		set = h.users[key]
+	case TargetTopic:
+		set = h.topics[key]
+	default:
+		set = h.conns
+	}
+	conns := make([]*Conn, 0, len(set))
+	for conn := range set {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+	for _, conn := range conns {
+		_ = conn.sendRaw(msg)
+	}
+}
+
+// receive 接收其他实例转发的消息, 订阅断开后重试
+func (h *Hub) receive(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := h.opt.Broker.Subscribe(ctx, func(env *Envelope) {
+			if env.Origin == h.id {
+				return
+			}
+			h.deliver(env.Target, env.Key, env.Data)
+		})
+		if err != nil && ctx.Err() == nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "websocket 消息订阅异常",
+			}).Error(err.Error())
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Online 用户在本实例上的连接数量
+func (h *Hub) Online(userID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userID])
+}
+
+// Count 本实例上的连接数量
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Close 关闭所有连接并停止接收转发消息
+func (h *Hub) Close() {
+	h.cancel()
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func encodeMessage(data any) ([]byte, error) {
+	switch v := data.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return json.Marshal(data)
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/web"
+	"catuan/web/webtest"
+	"reflect"
+	"testing"
+)
+
+func TestAfterHandlerRunsAfterAbort(t *testing.T) {
+	h := webtest.New()
+	called := make([]string, 0)
+	var seen *comm.RespResult
+
+	role := web.NewRole("user")
+	role.UseAfter(func(c *web.Context) {
+		called = append(called, "role.after")
+		seen = c.Response()
+	})
+	g := web.NewGroup("user", "order")
+	g.UseBefore(func(c *web.Context) {
+		called = append(called, "before")
+		c.Fail(comm.ErrForbidden)
+	}, "pay")
+	g.UseAfter(func(c *web.Context) {
+		called = append(called, "group.after")
+	})
+	g.UseAround(func(c *web.Context, next web.NextFunc) {
+		called = append(called, "around")
+		next()
+	})
+	g.BindAction("pay", func(c *web.Context) {
+		called = append(called, "action")
+	})
+	h.UseRole(role)
+	h.UseGroup(g)
+
+	res := h.Invoke("user", "order", "pay", nil, nil)
+	if res.ErrCode != comm.ErrCodeForbidden {
+		t.Fatalf("response: %s", res.Body)
+	}
+	expect := []string{"before", "group.after", "role.after"}
+	if !reflect.DeepEqual(called, expect) {
+		t.Errorf("called %v, expect %v", called, expect)
+	}
+	if seen == nil || seen.ErrCode != comm.ErrCodeForbidden {
+		t.Errorf("after handler should see the abort response: %+v", seen)
+	}
+}
+
+func TestAroundHandlerReplacesResponse(t *testing.T) {
+	h := webtest.New()
+	role := web.NewRole("user")
+	role.UseAround(func(c *web.Context, next web.NextFunc) {
+		next()
+		if resp := c.Response(); resp != nil && resp.ErrCode == comm.ErrCodeSuccess {
+			c.SetResponse(&comm.RespResult{ErrCode: comm.ErrCodeSuccess, ErrMsg: "wrapped", Data: resp.Data})
+		}
+	}, "order.list")
+	g := web.NewGroup("user", "order")
+	g.BindAction("list", func(c *web.Context) {
+		c.Result(comm.ErrCodeSuccess, "success", []int{1, 2})
+	})
+	g.BindAction("detail", func(c *web.Context) {
+		c.Result(comm.ErrCodeSuccess, "success", nil)
+	})
+	h.UseRole(role)
+	h.UseGroup(g)
+
+	if res := h.Invoke("user", "order", "list", nil, nil); res.ErrMsg != "wrapped" {
+		t.Errorf("list: %s", res.Body)
+	}
+	if res := h.Invoke("user", "order", "detail", nil, nil); res.ErrMsg != "success" {
+		t.Errorf("around should only wrap order.list: %s", res.Body)
+	}
+}
@@ -0,0 +1,44 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/auth"
+	"catuan/web"
+	"catuan/web/webtest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequireAuthResolvesJWTLazily(t *testing.T) {
+	h := webtest.New()
+	role := web.NewRole("user")
+	// 路由注册早于 JWT 初始化
+	role.RequireAuth(nil, true)
+	g := web.NewGroup("user", "profile")
+	g.BindAction("get", func(c *web.Context) {
+		c.Result(comm.ErrCodeSuccess, c.UserID(), nil)
+	})
+	h.UseRole(role)
+	h.UseGroup(g)
+
+	res := h.Invoke("user", "profile", "get", nil, map[string]string{"Authorization": "Bearer x.y.z"})
+	if res.Status != http.StatusInternalServerError {
+		t.Fatalf("without JWT the request should be rejected: %d %s", res.Status, res.Body)
+	}
+
+	j := auth.NewJWT("catuan", time.Hour)
+	if err := j.AddKey(auth.NewHSKey("k1", "secret"), true); err != nil {
+		t.Fatal(err)
+	}
+	h.SetJWT(j)
+	token, _ := j.Issue(auth.Claims{Subject: "1001", Role: "user"})
+	res = h.Invoke("user", "profile", "get", nil, map[string]string{"Authorization": "Bearer " + token})
+	if res.ErrCode != comm.ErrCodeSuccess || res.ErrMsg != "1001" {
+		t.Errorf("valid token: %s", res.Body)
+	}
+	res = h.Invoke("user", "profile", "get", nil, nil)
+	if res.ErrCode != comm.ErrCodeUnauthorized {
+		t.Errorf("missing token: %s", res.Body)
+	}
+}
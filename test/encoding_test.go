@@ -0,0 +1,67 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/web"
+	"catuan/web/webtest"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                                  web.EncodingJSON,
+		"*/*":                               web.EncodingJSON,
+		"application/xml":                   web.EncodingXML,
+		"application/xml, */*":              web.EncodingXML,
+		"application/json;q=0.5, text/xml":  web.EncodingXML,
+		"application/json, application/xml": web.EncodingJSON,
+		"application/json;q=0, */*":         web.EncodingXML,
+		"application/json;q=0, text/json;q=0, application/*": web.EncodingXML,
+		"application/msgpack;q=0.9, */*;q=0.1":               web.EncodingMsgPack,
+		"text/html":                                          "",
+		"*/*;q=0":                                            "",
+		"application/*;q=0, text/json":                       web.EncodingJSON,
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": web.EncodingXML,
+	} {
+		if got := web.NegotiateEncoding(accept); got != want {
+			t.Errorf("NegotiateEncoding(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+type encodingOrder struct {
+	OrderID int    `json:"order_id"`
+	Remark  string `json:"remark,omitempty"`
+	secret  string
+}
+
+func TestEncodingResponse(t *testing.T) {
+	h := webtest.New()
+	calls := 0
+	g := web.NewGroup("user", "order")
+	g.BindAction("detail", func(c *web.Context) {
+		calls++
+		c.Result(comm.ErrCodeSuccess, "success", encodingOrder{OrderID: 7, secret: "s"})
+	})
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+
+	res := h.Invoke("user", "order", "detail", nil, map[string]string{"Accept": "text/html"})
+	if res.Status != http.StatusNotAcceptable || res.ErrCode != comm.ErrCodeNotAcceptable {
+		t.Errorf("unacceptable: %d %s", res.Status, res.Body)
+	}
+	if calls != 0 {
+		t.Error("action should not run when no encoding is acceptable")
+	}
+
+	res = h.Invoke("user", "order", "detail", nil, map[string]string{"Accept": "application/json;q=0, application/xml"})
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "application/xml") {
+		t.Fatalf("content type %s", res.Header.Get("Content-Type"))
+	}
+	body := string(res.Body)
+	if !strings.Contains(body, "<data><order_id>7</order_id></data>") || strings.Contains(body, "OrderID") {
+		t.Errorf("xml should use json field names: %s", body)
+	}
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"catuan/comm"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestErrorRegistry(t *testing.T) {
+	orderNotFound := comm.NewError(10404, "order.not_found", "订单不存在", http.StatusOK)
+	if e, ok := comm.LookupError(10404); !ok || e != orderNotFound {
+		t.Fatal("registered error should be found by code")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("duplicate code should panic")
+			}
+		}()
+		comm.NewError(10404, "order.missing", "订单不存在", 0)
+	}()
+
+	cause := errors.New("record not found")
+	err := fmt.Errorf("query order: %w", orderNotFound.Wrap(cause))
+	if !errors.Is(err, orderNotFound) || !errors.Is(err, cause) {
+		t.Error("wrapped error should match both the code and the cause")
+	}
+	var e *comm.Error
+	if !errors.As(err, &e) || e.Result().ErrCode != 10404 || e.Result().ErrMsg != "订单不存在" {
+		t.Errorf("unexpected result: %+v", e)
+	}
+	if errors.Is(err, comm.ErrNotFound) {
+		t.Error("different codes should not match")
+	}
+}
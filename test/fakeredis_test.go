@@ -0,0 +1,186 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis 仅实现分布式锁用到的命令, lua 脚本按内容匹配对应的比较操作
+type fakeRedis struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	data map[string]fakeEntry
+}
+
+type fakeEntry struct {
+	value   string
+	expires time.Time
+}
+
+func newFakeRedis(t *testing.T) (*fakeRedis, *redis.Client) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, data: make(map[string]fakeEntry)}
+	go f.serve()
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String()})
+	t.Cleanup(func() {
+		client.Close()
+		ln.Close()
+	})
+	return f, client
+}
+
+// Set 模拟其他实例写入, ttl 为 0 时不过期
+func (f *fakeRedis) Set(key, value string, ttl time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := fakeEntry{value: value}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	f.data[key] = e
+}
+
+func (f *fakeRedis) Get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.get(key)
+	return e.value, ok
+}
+
+// get 需持有 mu
+func (f *fakeRedis) get(key string) (fakeEntry, bool) {
+	e, ok := f.data[key]
+	if ok && !e.expires.IsZero() && !time.Now().Before(e.expires) {
+		delete(f.data, key)
+		return fakeEntry{}, false
+	}
+	return e, ok
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err = io.WriteString(conn, f.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch strings.ToLower(args[0]) {
+	case "ping":
+		return "+PONG\r\n"
+	case "get":
+		if e, ok := f.get(args[1]); ok {
+			return bulkReply(e.value)
+		}
+		return "$-1\r\n"
+	case "set":
+		return f.set(args[1:])
+	case "evalsha":
+		return "-NOSCRIPT No matching script\r\n"
+	case "eval":
+		// eval script numkeys key token [ttl]
+		script, key, token := args[1], args[3], args[4]
+		e, ok := f.get(key)
+		if !ok || e.value != token {
+			return ":0\r\n"
+		}
+		switch {
+		case strings.Contains(script, "DEL"):
+			delete(f.data, key)
+		case strings.Contains(script, "PEXPIRE"):
+			ms, _ := strconv.Atoi(args[5])
+			e.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			f.data[key] = e
+		default:
+			return "-ERR unsupported script\r\n"
+		}
+		return ":1\r\n"
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+// set 支持 SET key value [EX s|PX ms] [NX]
+func (f *fakeRedis) set(args []string) string {
+	e := fakeEntry{value: args[1]}
+	nx := false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "nx":
+			nx = true
+		case "ex", "px":
+			n, _ := strconv.Atoi(args[i+1])
+			unit := time.Second
+			if strings.ToLower(args[i]) == "px" {
+				unit = time.Millisecond
+			}
+			e.expires = time.Now().Add(time.Duration(n) * unit)
+			i++
+		}
+	}
+	if _, ok := f.get(args[0]); ok && nx {
+		return "$-1\r\n"
+	}
+	f.data[args[0]] = e
+	return "+OK\r\n"
+}
+
+func bulkReply(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"catuan/web"
+	"catuan/web/webtest"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMountGroupAcrossRoles(t *testing.T) {
+	h := webtest.New()
+	g := web.NewGroup("", "order")
+	sub := g.SubGroup("item")
+	h.MountGroup(g, "user", "admin")
+	for _, path := range [][2]string{{"user", "order"}, {"admin", "order"}, {"user", "order.item"}, {"admin", "order.item"}} {
+		found, ok := h.FindGroup(path[0], path[1])
+		if !ok {
+			t.Errorf("%s/%s not mounted", path[0], path[1])
+			continue
+		}
+		if path[1] == "order.item" && found != web.GroupInf(sub) {
+			t.Errorf("%s/%s should be the sub group", path[0], path[1])
+		}
+	}
+}
+
+func TestMountGroupDuplicateIsAtomic(t *testing.T) {
+	h := webtest.New()
+	h.MountGroup(web.NewGroup("", "order"), "admin")
+
+	g := web.NewGroup("", "order")
+	g.SubGroup("item")
+	func() {
+		defer func() {
+			r := recover()
+			if r == nil || !strings.Contains(r.(string), "admin/order") {
+				t.Errorf("panic should name the duplicate path: %v", r)
+			}
+		}()
+		h.MountGroup(g, "user", "admin")
+	}()
+	// 冲突时 user 下也不能有部分挂载
+	if _, ok := h.FindGroup("user", "order"); ok {
+		t.Error("user/order should not be mounted after a failed MountGroup")
+	}
+	if _, ok := h.FindGroup("user", "order.item"); ok {
+		t.Error("user/order.item should not be mounted after a failed MountGroup")
+	}
+}
+
+func TestSubGroupInheritsHandlers(t *testing.T) {
+	called := make([]string, 0)
+	mark := func(name string) web.HandlerFunc {
+		return func(c *web.Context) {
+			called = append(called, name)
+		}
+	}
+	wrap := func(name string) web.AroundFunc {
+		return func(c *web.Context, next web.NextFunc) {
+			called = append(called, name+".in")
+			next()
+			called = append(called, name+".out")
+		}
+	}
+	g := web.NewGroup("user", "order")
+	g.UseBefore(mark("parent"))
+	g.UseAround(wrap("parent"))
+	sub := g.SubGroup("item")
+	sub.UseBefore(mark("child"))
+	sub.UseAround(wrap("child"))
+	sub.BindAction("list", mark("action"))
+
+	h := webtest.New()
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+
+	// 子分组标签是单个路径段, 与 /:role/:group/:action 路由及接口文档一致
+	if sub.GroupLabel() != "order.item" {
+		t.Fatalf("sub group label %s", sub.GroupLabel())
+	}
+	if _, ok := h.OpenAPI(web.OpenAPIOption{}).Paths["/user/order.item/list"]; !ok {
+		t.Error("openapi path should use the sub group label")
+	}
+	res := h.Invoke("user", "order.item", "list", nil, nil)
+	if res.Status != http.StatusOK {
+		t.Fatalf("invoke: %d %s", res.Status, res.Body)
+	}
+	expect := []string{"parent", "child", "parent.in", "child.in", "action", "child.out", "parent.out"}
+	if !reflect.DeepEqual(called, expect) {
+		t.Errorf("handler order %v, expect %v", called, expect)
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"catuan/web"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHandlerOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c := web.NewContext(ginCtx)
+	c.InitRoleInfo("user", "order", "list")
+
+	called := make([]string, 0)
+	mark := func(name string) web.HandlerFunc {
+		return func(ctx *web.Context) {
+			called = append(called, name)
+		}
+	}
+
+	role := web.NewRole("user")
+	role.UseBefore(10, mark("role-10"))
+	role.UseBefore(-1, mark("role--1"))
+	role.UseBefore(10, mark("role-10b"))
+	var roleInf web.RoleInf = role
+	roleInf.Call(c)
+
+	group := web.NewGroup("user", "order")
+	group.UseBefore(mark("group-0"))
+	group.UseBeforeWithOrder(-5, mark("group--5"), "list")
+	group.UseBeforeWithOrder(0, mark("group-0b"), "list")
+	group.UseBeforeWithOrder(1, mark("group-1"), "detail")
+	group.BindAction("list", mark("action"))
+	group.Call(c)
+
+	expect := []string{"role--1", "role-10", "role-10b", "group--5", "group-0", "group-0b", "action"}
+	if !reflect.DeepEqual(called, expect) {
+		t.Errorf("handler order %v, expect %v", called, expect)
+	}
+}
+
+func TestHandlerOrderCommonFirst(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c := web.NewContext(ginCtx)
+	c.InitRoleInfo("user", "order", "list")
+
+	called := make([]string, 0)
+	mark := func(name string) web.HandlerFunc {
+		return func(ctx *web.Context) {
+			called = append(called, name)
+		}
+	}
+
+	// action handler 先注册, 相同 order 下仍在公共 handler 之后执行
+	parent := web.NewGroup("user", "order")
+	parent.UseBefore(mark("action"), "list")
+	parent.UseBefore(mark("common"))
+	parent.BindAction("list", mark("handler"))
+	parent.Call(c)
+
+	expect := []string{"common", "action", "handler"}
+	if !reflect.DeepEqual(called, expect) {
+		t.Errorf("handler order %v, expect %v", called, expect)
+	}
+
+	role := web.NewRole("user")
+	role.UseBefore(0, mark("role"))
+	chain := role.BeforeChain()
+	chain[0].Order = 100
+	if role.BeforeChain()[0].Order != 0 {
+		t.Error("BeforeChain should return a copy")
+	}
+}
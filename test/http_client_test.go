@@ -0,0 +1,328 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/http_client"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerStateTransitions(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	comm.SetClock(func() time.Time { return now })
+	defer comm.SetClock(nil)
+
+	b := http_client.NewBreaker(http_client.BreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		SuccessThreshold: 2,
+	})
+	record := func(success bool) {
+		t.Helper()
+		done, err := b.Allow("api")
+		if err != nil {
+			t.Fatalf("allow: %v", err)
+		}
+		done(success)
+	}
+
+	record(false)
+	record(true) // 成功后重新计数
+	record(false)
+	if s := b.State("api"); s != http_client.StateClosed {
+		t.Fatalf("state %s", s)
+	}
+	record(false)
+	if s := b.State("api"); s != http_client.StateOpen {
+		t.Fatalf("state %s", s)
+	}
+	if _, err := b.Allow("api"); err != http_client.ErrCircuitOpen {
+		t.Fatalf("open breaker should reject: %v", err)
+	}
+	if s := b.State("other"); s != http_client.StateClosed {
+		t.Errorf("other host state %s", s)
+	}
+
+	now = now.Add(time.Minute)
+	if s := b.State("api"); s != http_client.StateHalfOpen {
+		t.Fatalf("state %s", s)
+	}
+	done, err := b.Allow("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.Allow("api"); err != http_client.ErrCircuitOpen {
+		t.Errorf("half-open should limit probes: %v", err)
+	}
+	done(false)
+	if s := b.State("api"); s != http_client.StateOpen {
+		t.Fatalf("failed probe should reopen: %s", s)
+	}
+
+	now = now.Add(time.Minute)
+	record(true)
+	if s := b.State("api"); s != http_client.StateHalfOpen {
+		t.Fatalf("state %s", s)
+	}
+	record(true)
+	if s := b.State("api"); s != http_client.StateClosed {
+		t.Fatalf("state %s", s)
+	}
+}
+
+func TestBreakerIgnoresCallerCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	client, err := http_client.NewBuilder().
+		Breaker(http_client.BreakerConfig{FailureThreshold: 1}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		_, err = client.Do(req)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+
+	// host 超时属于上游问题, 计为失败
+	u, _ := url.Parse(srv.URL)
+	client, _ = http_client.NewBuilder().
+		HostTimeout(u.Host, time.Millisecond*20).
+		Breaker(http_client.BreakerConfig{FailureThreshold: 1}).
+		Build()
+	if _, err = client.Get(srv.URL); err == nil {
+		t.Fatal("host timeout should fail")
+	}
+	if _, err = client.Get(srv.URL); !errors.Is(err, http_client.ErrCircuitOpen) {
+		t.Errorf("host timeout should open the breaker: %v", err)
+	}
+}
+
+func TestBreakerCallerCancelKeepsClosed(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	client, _ := http_client.NewBuilder().
+		Breaker(http_client.BreakerConfig{FailureThreshold: 1}).
+		Build()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		cancel()
+	}()
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled request: %v", err)
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel2()
+	req, _ = http.NewRequestWithContext(ctx2, http.MethodGet, srv.URL, nil)
+	if _, err := client.Do(req); errors.Is(err, http_client.ErrCircuitOpen) {
+		t.Error("caller cancellation should not open the breaker")
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("upstream calls %d", n)
+	}
+}
+
+func TestBreakerCountsClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	// client 超时说明上游无响应, 计为失败
+	client, _ := http_client.NewBuilder().
+		Timeout(time.Millisecond * 20).
+		Breaker(http_client.BreakerConfig{FailureThreshold: 2}).
+		Build()
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(srv.URL); err == nil || errors.Is(err, http_client.ErrCircuitOpen) {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, http_client.ErrCircuitOpen) {
+		t.Errorf("client timeout should open the breaker: %v", err)
+	}
+}
+
+func TestRetryIdempotentRequests(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client, _ := http_client.NewBuilder().
+		Retry(http_client.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond * 5}).
+		Build()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("status %d after %d calls", resp.StatusCode, calls)
+	}
+
+	// POST 未携带 Idempotency-Key 时不重试
+	atomic.StoreInt32(&calls, 0)
+	resp, err = client.Post(srv.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("post: status %d after %d calls", resp.StatusCode, calls)
+	}
+
+	// 携带 Idempotency-Key 时重试并重新发送 body
+	atomic.StoreInt32(&calls, 0)
+	bodies := make(chan string, 3)
+	postSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- string(b)
+		if atomic.AddInt32(&calls, 1) < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer postSrv.Close()
+	req, _ := http.NewRequest(http.MethodPost, postSrv.URL, strings.NewReader(`{"id":1}`))
+	req.Header.Set("Idempotency-Key", "k1")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("idempotent post: status %d after %d calls", resp.StatusCode, calls)
+	}
+	for i := 0; i < 2; i++ {
+		if b := <-bodies; b != `{"id":1}` {
+			t.Errorf("attempt %d body %q", i, b)
+		}
+	}
+}
+
+func TestRetryStopsOnCallerCancel(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client, _ := http_client.NewBuilder().
+		Retry(http_client.RetryPolicy{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: time.Second}).
+		Build()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*30)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	_, err := client.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err: %v", err)
+	}
+	// 退避等待中也应立即返回, 不会等到重试次数用完
+	if time.Since(start) > time.Millisecond*200 || atomic.LoadInt32(&calls) > 10 {
+		t.Errorf("retry should stop when the caller gives up: %d calls in %s", calls, time.Since(start))
+	}
+}
+
+func TestHostTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Millisecond * 200):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte("slow"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	client, _ := http_client.NewBuilder().HostTimeout(u.Hostname(), time.Millisecond*20).Build()
+	start := time.Now()
+	if _, err := client.Get(srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("host timeout: %v", err)
+	}
+	if time.Since(start) > time.Millisecond*150 {
+		t.Errorf("host timeout took %s", time.Since(start))
+	}
+
+	// 其他 host 不受影响
+	client, _ = http_client.NewBuilder().HostTimeout("other.example.com", time.Millisecond*20).Build()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "slow" {
+		t.Errorf("body %q: %v", body, err)
+	}
+}
+
+func TestRedactorBody(t *testing.T) {
+	r := http_client.NewRedactor(http_client.DefaultRedactFields...)
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		leak        string
+	}{
+		{"json", "application/json", `{"openid":"o-123","data":{"session_key":"sk-1"}}`, "sk-1"},
+		{"truncated json", "application/json", `{"openid":"o-123","items":[1,2,3],"access_token":"tok-ab`, "tok-ab"},
+		{"escaped quote", "application/json", `{"sign":"a\"b-leak","x":`, "b-leak"},
+		{"number", "text/plain", `{"secret": 123456, "x": 1`, "123456"},
+		{"xml", "text/xml", `<xml><sign type="md5">S-1</sign><openid>o-1</openid></xml>`, "S-1"},
+		{"truncated xml", "text/xml", `<xml><appid>wx1</appid><sign>S-trunc`, "S-trunc"},
+		{"form", "application/x-www-form-urlencoded", `a=1&secret=s-1&b=2`, "s-1"},
+		{"plain", "text/plain", `code=1 js_code=jc-1`, "jc-1"},
+	}
+	for _, c := range cases {
+		got := r.Body(c.contentType, []byte(c.body))
+		if strings.Contains(got, c.leak) || strings.Contains(got, "o-1") {
+			t.Errorf("%s: %s", c.name, got)
+		}
+		if !strings.Contains(got, "***") {
+			t.Errorf("%s: no redaction: %s", c.name, got)
+		}
+	}
+	if got := r.Body("text/xml", []byte(`<xml><appid>wx1</appid></xml>`)); got != `<xml><appid>wx1</appid></xml>` {
+		t.Errorf("non-sensitive content should be kept: %s", got)
+	}
+}
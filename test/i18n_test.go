@@ -0,0 +1,71 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/i18n"
+	"catuan/web"
+	"catuan/web/webtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestI18nBundle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"zh-CN.yaml": "order:\n  not_found: \"订单 {id} 不存在\"\n",
+		"en.json":    `{"order": {"not_found": "Order {id} not found"}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := i18n.NewBundle("zh-CN")
+	if err := b.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	args := map[string]any{"id": 42}
+	if got := b.T("en", "order.not_found", args); got != "Order 42 not found" {
+		t.Errorf("en: %s", got)
+	}
+	if got := b.T("fr", "order.not_found", args); got != "订单 42 不存在" {
+		t.Errorf("fallback to default locale: %s", got)
+	}
+	for accept, want := range map[string]string{
+		"":                      "zh-CN",
+		"en-GB,en;q=0.8":        "en",
+		"fr;q=0.9, zh-TW;q=0.5": "zh-CN",
+		"zh-CN;q=0.2, en;q=0.7": "en",
+		"de, *;q=0.1":           "zh-CN",
+	} {
+		if got := b.Match(accept); got != want {
+			t.Errorf("Match(%q) = %s, want %s", accept, got, want)
+		}
+	}
+}
+
+type reqSignup struct {
+	Email string `json:"email_address" binding:"required,email"`
+}
+
+func TestValidationJSONFieldNames(t *testing.T) {
+	web.UseJSONFieldNames()
+	h := webtest.New()
+	g := web.NewGroup("user", "account")
+	g.BindAction("signup", func(c *web.Context) {
+		req := reqSignup{}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Fail(c.ValidationError(err))
+			return
+		}
+		c.Result(comm.ErrCodeSuccess, "success")
+	})
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+
+	res := h.Invoke("user", "account", "signup", map[string]string{"email_address": "bad"}, map[string]string{"Accept-Language": "en"})
+	if res.ErrMsg != "email_address must be a valid email" {
+		t.Errorf("validation message: %s", res.Body)
+	}
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/idempotency"
+	"catuan/web"
+	"catuan/web/webtest"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyReplayKeepsStatus(t *testing.T) {
+	h := webtest.New()
+	calls := 0
+	g := web.NewGroup("user", "order")
+	g.UseAround(web.Idempotency(web.IdempotencyOption{Store: idempotency.NewMemoryStore()}))
+	g.BindAction("cancel", func(c *web.Context) {
+		calls++
+		c.Fail(comm.ErrNotFound.WithKey("order.not_found", "订单 {id} 不存在").WithArgs(map[string]any{"id": 42}))
+	})
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+
+	headers := map[string]string{"Idempotency-Key": "k1"}
+	first := h.Invoke("user", "order", "cancel", nil, headers)
+	second := h.Invoke("user", "order", "cancel", nil, headers)
+	if calls != 1 {
+		t.Fatalf("action called %d times", calls)
+	}
+	if second.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("second request should be replayed")
+	}
+	if second.Status != http.StatusNotFound || second.ErrCode != comm.ErrCodeNotFound {
+		t.Errorf("replayed status %d: %s", second.Status, second.Body)
+	}
+	if second.ErrMsg != first.ErrMsg {
+		t.Errorf("replayed message %q, expect %q", second.ErrMsg, first.ErrMsg)
+	}
+}
+
+func TestIdempotencyServerErrorNotStored(t *testing.T) {
+	h := webtest.New()
+	calls := 0
+	g := web.NewGroup("user", "order")
+	g.UseAround(web.Idempotency(web.IdempotencyOption{Store: idempotency.NewMemoryStore()}))
+	g.BindAction("pay", func(c *web.Context) {
+		calls++
+		if calls == 1 {
+			c.Fail(comm.ErrFail)
+			return
+		}
+		c.Result(comm.ErrCodeSuccess, "success", nil)
+	})
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+
+	headers := map[string]string{"Idempotency-Key": "k1"}
+	if res := h.Invoke("user", "order", "pay", nil, headers); res.Status != http.StatusInternalServerError {
+		t.Fatalf("first: %d %s", res.Status, res.Body)
+	}
+	if res := h.Invoke("user", "order", "pay", nil, headers); res.ErrCode != comm.ErrCodeSuccess {
+		t.Errorf("retry after 5xx should run again: %s", res.Body)
+	}
+	if res := h.Invoke("user", "order", "pay", nil, headers); res.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("success should be replayed")
+	}
+	if calls != 2 {
+		t.Errorf("action called %d times", calls)
+	}
+}
+
+func TestIdempotencyReleaseChecksToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	comm.SetClock(func() time.Time { return now })
+	defer comm.SetClock(nil)
+
+	ctx := context.Background()
+	store := idempotency.NewMemoryStore()
+	first, acquired, err := store.Begin(ctx, "k", time.Minute)
+	if err != nil || !acquired {
+		t.Fatalf("begin: %v %v", acquired, err)
+	}
+	// 首个请求超时, 占用被第二个请求取得
+	now = now.Add(2 * time.Minute)
+	second, acquired, err := store.Begin(ctx, "k", time.Minute)
+	if err != nil || !acquired {
+		t.Fatalf("begin after expiry: %v %v", acquired, err)
+	}
+	if err = store.Release(ctx, "k", first.Token); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := store.Get(ctx, "k"); rec == nil || rec.Token != second.Token {
+		t.Fatal("stale token should not release the new lock")
+	}
+	resp := &comm.RespResult{ErrCode: comm.ErrCodeSuccess, ErrMsg: "success"}
+	if err = store.Complete(ctx, "k", first.Token, resp, time.Hour); err != idempotency.ErrLockLost {
+		t.Errorf("stale complete: %v", err)
+	}
+	if err = store.Complete(ctx, "k", second.Token, resp, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdempotencyWaitStopsWithRequest(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	// 首个请求处理中
+	if _, acquired, err := store.Begin(context.Background(), "user:order:pay::k1", time.Minute); err != nil || !acquired {
+		t.Fatalf("begin: %v %v", acquired, err)
+	}
+	h := webtest.New()
+	g := web.NewGroup("user", "order")
+	g.UseAround(web.Idempotency(web.IdempotencyOption{Store: store, Wait: time.Second * 5}))
+	g.BindAction("pay", func(c *web.Context) {
+		c.Result(comm.ErrCodeSuccess, "success", nil)
+	}, web.WithTimeout(time.Millisecond*100))
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+
+	start := time.Now()
+	res := h.Invoke("user", "order", "pay", nil, map[string]string{"Idempotency-Key": "k1"})
+	if time.Since(start) > time.Second {
+		t.Errorf("wait should stop with the request context, took %s", time.Since(start))
+	}
+	if res.ErrCode != comm.ErrCodeConflict {
+		t.Errorf("pending duplicate: %s", res.Body)
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"catuan/components/auth"
+	"catuan/web"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTRotation(t *testing.T) {
+	j := auth.NewJWT("catuan", time.Hour)
+	if err := j.AddKey(auth.NewHSKey("k1", "old-secret"), true); err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := j.Issue(auth.Claims{Subject: "1001", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = j.AddKey(auth.NewHSKey("k2", "new-secret"), true); err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := j.Issue(auth.Claims{Subject: "1002", Role: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := j.Verify(oldToken)
+	if err != nil || claims.Subject != "1001" || claims.Role != "user" {
+		t.Errorf("verify old token: %v %v", claims, err)
+	}
+	claims, err = j.Verify(newToken)
+	if err != nil || claims.Subject != "1002" {
+		t.Errorf("verify new token: %v %v", claims, err)
+	}
+
+	j.RemoveKey("k1")
+	if _, err = j.Verify(oldToken); err != auth.ErrKeyNotFound {
+		t.Errorf("removed key should fail, got %v", err)
+	}
+
+	parts := strings.Split(newToken, ".")
+	if _, err = j.Verify(parts[0] + "." + parts[1] + "."); err == nil {
+		t.Error("token without signature should fail")
+	}
+
+	expired, _ := j.Issue(auth.Claims{Subject: "1003", ExpiresAt: time.Now().Add(-time.Hour).Unix()})
+	if _, err = j.Verify(expired); err != auth.ErrTokenExpired {
+		t.Errorf("expired token should fail, got %v", err)
+	}
+}
+
+func TestJWTAudience(t *testing.T) {
+	j := auth.NewJWT("catuan", time.Hour)
+	if err := j.AddKey(auth.NewHSKey("k1", "secret"), true); err != nil {
+		t.Fatal(err)
+	}
+	j.SetAudience("mall")
+	token, err := j.Issue(auth.Claims{Subject: "1001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := j.Verify(token); err != nil || claims.Audience != "mall" {
+		t.Errorf("verify: %v %v", claims, err)
+	}
+	other, _ := j.Issue(auth.Claims{Subject: "1001", Audience: "admin"})
+	if _, err := j.Verify(other); err != auth.ErrTokenInvalid {
+		t.Errorf("audience mismatch should be invalid: %v", err)
+	}
+}
+
+func TestJWTConfigValidation(t *testing.T) {
+	for conf, want := range map[string]string{
+		"jwt:\n  keys:\n    - kid: k1\n      alg: none\n      secret: s\n": "unknown alg",
+		"jwt:\n  keys:\n    - kid: k1\n      secret: s\n":                  "unknown alg",
+		"jwt:\n  keys:\n    - kid: k1\n      alg: HS256\n":                 "empty secret",
+		"jwt:\n  keys:\n    - kid: k1\n      alg: RS256\n":                 "empty key file",
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "application.yaml"), []byte(conf), 0644); err != nil {
+			t.Fatal(err)
+		}
+		func() {
+			defer func() {
+				r := recover()
+				if msg, _ := r.(string); !strings.Contains(msg, want) {
+					t.Errorf("%q should fail at config load: %v", want, r)
+				}
+			}()
+			web.New("test", dir)
+		}()
+	}
+}
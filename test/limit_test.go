@@ -0,0 +1,248 @@
+package test
+
+import (
+	"catuan/components/limits"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUniLimit(t *testing.T) {
+	l := limits.NewUniLimitWithTTL[string](time.Millisecond * 50)
+	if !l.Check("order-1") || l.Check("order-1") {
+		t.Fatal("key should be locked once")
+	}
+	time.Sleep(time.Millisecond * 60)
+	if !l.Check("order-1") {
+		t.Error("expired key should be released")
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		l.Release("order-1")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*30)
+	defer cancel()
+	if err := l.Acquire(ctx, "order-1"); err != nil {
+		t.Errorf("acquire after release: %v", err)
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel2()
+	if err := l.Acquire(ctx2, "order-1"); err != context.DeadlineExceeded {
+		t.Errorf("acquire locked key should time out, got %v", err)
+	}
+	l.Release("order-1")
+
+	func() {
+		defer func() {
+			recover()
+		}()
+		l.Do("order-2", func() {
+			panic("handler panic")
+		})
+	}()
+	if !l.Do("order-2", func() {}) {
+		t.Error("key should be released after panic")
+	}
+
+	bounded := limits.NewBoundedUniLimit[int64](2, 0)
+	if !bounded.Check(1) || !bounded.Check(2) || bounded.Check(3) {
+		t.Error("bounded limit should reject the third key")
+	}
+	bounded.Release(1)
+	if !bounded.Check(3) {
+		t.Error("bounded limit should accept after release")
+	}
+}
+
+// waitQueued 等待 key 的排队数量达到 n
+func waitQueued(t *testing.T, s *limits.Semaphore[string], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for s.Stats(key).Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("waiting %d, expect %d", s.Stats(key).Waiting, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	s := limits.NewSemaphore[string](1)
+	if !s.TryAcquire("m1") {
+		t.Fatal("first acquire should succeed")
+	}
+	var mu sync.Mutex
+	order := make([]int, 0)
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := s.Acquire(context.Background(), "m1"); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			s.Release("m1")
+		}(i)
+		waitQueued(t, s, "m1", i)
+	}
+	if s.TryAcquire("m1") {
+		t.Error("TryAcquire should not jump the queue")
+	}
+	s.Release("m1")
+	wg.Wait()
+	if !reflect.DeepEqual(order, []int{1, 2, 3}) {
+		t.Errorf("order %v", order)
+	}
+	if stats := s.Stats("m1"); stats.Holders != 0 || stats.Waiting != 0 {
+		t.Errorf("stats after release: %+v", stats)
+	}
+}
+
+func TestSemaphoreCancel(t *testing.T) {
+	s := limits.NewSemaphore[string](1)
+	s.TryAcquire("m1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		cancelled <- s.Acquire(ctx, "m1")
+	}()
+	waitQueued(t, s, "m1", 1)
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- s.Acquire(context.Background(), "m1")
+	}()
+	waitQueued(t, s, "m1", 2)
+
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Fatalf("cancelled waiter: %v", err)
+	}
+	waitQueued(t, s, "m1", 1)
+	s.Release("m1")
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("next waiter should get the permit after the head cancels")
+	}
+	if stats := s.Stats("m1"); stats.Holders != 1 || stats.Waiting != 0 {
+		t.Errorf("stats: %+v", stats)
+	}
+
+	// 调大容量时唤醒等待者
+	go func() {
+		acquired <- s.Acquire(context.Background(), "m1")
+	}()
+	waitQueued(t, s, "m1", 1)
+	s.SetCapacity("m1", 2)
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+	if stats := s.Stats("m1"); stats.Holders != 2 || stats.Capacity != 2 {
+		t.Errorf("stats after SetCapacity: %+v", stats)
+	}
+}
+
+func TestRedisLockOwnerRelease(t *testing.T) {
+	fake, client := newFakeRedis(t)
+	ctx := context.Background()
+	l := limits.NewRedisLock(client, "lock:", time.Second)
+	lock, ok := l.Check(ctx, "order-1")
+	if !ok {
+		t.Fatal("first check should acquire the lock")
+	}
+	if _, ok = l.Check(ctx, "order-1"); ok {
+		t.Fatal("locked key should not be acquired again")
+	}
+	if v, _ := fake.Get("lock:order-1"); v != lock.Token() {
+		t.Fatalf("stored token %q", v)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.Get("lock:order-1"); ok {
+		t.Error("released lock should be deleted")
+	}
+
+	// 锁过期后被他人获取, 原持有者释放时不能删除他人的锁
+	lock, ok = l.Check(ctx, "order-2")
+	if !ok {
+		t.Fatal("check order-2")
+	}
+	fake.Set("lock:order-2", "other", time.Minute)
+	if err := lock.Release(ctx); !errors.Is(err, limits.ErrLockLost) {
+		t.Errorf("release of a lost lock: %v", err)
+	}
+	if v, _ := fake.Get("lock:order-2"); v != "other" {
+		t.Errorf("other holder's lock was removed: %q", v)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Errorf("repeated release: %v", err)
+	}
+}
+
+func TestRedisLockRenewAndLost(t *testing.T) {
+	fake, client := newFakeRedis(t)
+	ctx := context.Background()
+	l := limits.NewRedisLock(client, "lock:", time.Millisecond*150)
+	lock, ok := l.Check(ctx, "order-1")
+	if !ok {
+		t.Fatal("check should acquire the lock")
+	}
+	// 持有时间超过过期时间, 续期后仍然有效
+	time.Sleep(time.Millisecond * 400)
+	if v, _ := fake.Get("lock:order-1"); v != lock.Token() || lock.Err() != nil {
+		t.Fatalf("renewed lock: %q %v", v, lock.Err())
+	}
+	if _, ok = l.Check(ctx, "order-1"); ok {
+		t.Fatal("renewed lock should still be held")
+	}
+
+	// 锁被他人获取后通知持有者
+	fake.Set("lock:order-1", "other", time.Minute)
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lost lease should be signalled")
+	}
+	if !errors.Is(lock.Err(), limits.ErrLockLost) {
+		t.Errorf("err after lost: %v", lock.Err())
+	}
+}
+
+func TestRedisLockExpiresAfterHolderDies(t *testing.T) {
+	fake, client := newFakeRedis(t)
+	l := limits.NewRedisLock(client, "lock:", time.Second)
+	// 持有者退出后不再续期, 锁在过期后可被获取
+	fake.Set("lock:order-1", "dead", time.Millisecond*100)
+	if _, err := l.AcquireTimeout(context.Background(), "order-1", time.Millisecond*30); err != context.DeadlineExceeded {
+		t.Fatalf("acquire held lock: %v", err)
+	}
+	start := time.Now()
+	lock, err := l.AcquireTimeout(context.Background(), "order-1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release(context.Background())
+	if time.Since(start) > time.Millisecond*800 {
+		t.Errorf("acquired after %s", time.Since(start))
+	}
+
+	// 调用方取消时立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = l.Acquire(ctx, "order-1"); err != context.Canceled {
+		t.Errorf("acquire with cancelled ctx: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"bytes"
+	"catuan/comm"
+	"catuan/components/metrics"
+	"catuan/web"
+	"catuan/web/webtest"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMetricsText(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Counter("jobs_total", "Jobs done.", "queue").Add(2, "mail")
+	r.Gauge("queue_size", "Queue size.", "queue").Set(5, `a"b`)
+	h := r.Histogram("job_seconds", "Job latency.", []float64{1, 0.1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	r.RegisterCollector(func() []metrics.Family {
+		return []metrics.Family{{Name: "pool_idle", Help: "Idle.", Type: metrics.TypeGauge, Samples: []metrics.Sample{{Labels: map[string]string{"db": "0"}, Value: 3}}}}
+	})
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE jobs_total counter",
+		`jobs_total{queue="mail"} 2`,
+		`queue_size{queue="a\"b"} 5`,
+		`job_seconds_bucket{le="0.1"} 1`,
+		`job_seconds_bucket{le="1"} 2`,
+		`job_seconds_bucket{le="+Inf"} 2`,
+		"job_seconds_sum 0.55",
+		"job_seconds_count 2",
+		`pool_idle{db="0"} 3`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+	if r.Counter("jobs_total", "Jobs done.", "queue") == nil {
+		t.Error("counter should be reused")
+	}
+}
+
+func TestAppMetricsIsolated(t *testing.T) {
+	metrics.NewCounter("catuan_test_custom_total", "Custom metric.").Inc()
+	apps := make([]*webtest.Harness, 0)
+	for i := 0; i < 2; i++ {
+		h := webtest.New()
+		g := web.NewGroup("user", "order")
+		g.BindAction("list", func(c *web.Context) {
+			c.Result(comm.ErrCodeSuccess, "success", nil)
+		})
+		h.UseRole(web.NewRole("user"))
+		h.UseGroup(g)
+		apps = append(apps, h)
+	}
+	apps[0].Invoke("user", "order", "list", nil, nil)
+	apps[0].Invoke("user", "order", "list", nil, nil)
+	apps[1].Invoke("user", "order", "list", nil, nil)
+
+	for i, h := range apps {
+		var buf bytes.Buffer
+		if err := h.Metrics().WriteText(&buf); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		seen := make(map[string]bool)
+		for _, line := range strings.Split(out, "\n") {
+			if !strings.HasPrefix(line, "# TYPE ") {
+				continue
+			}
+			if seen[line] {
+				t.Errorf("app %d: duplicate family %q", i, line)
+			}
+			seen[line] = true
+		}
+		want := fmt.Sprintf(`catuan_requests_total{action="list",code="0",group="order",role="user"} %d`, 2-i)
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("app %d: missing %q in:\n%s", i, want, out)
+		}
+		if !strings.Contains(out, "catuan_test_custom_total 1\n") {
+			t.Errorf("app %d: custom metric missing", i)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"catuan/web"
+	"catuan/web/webtest"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type reqCreateOrder struct {
+	Title string `json:"title" binding:"required"`
+	Count int    `json:"count"`
+}
+
+type respCreateOrder struct {
+	ID      int64     `json:"id"`
+	Tags    []string  `json:"tags"`
+	Created time.Time `json:"created"`
+}
+
+// treeNode 嵌入自身且字段引用自身
+type treeNode struct {
+	*treeNode
+	Name     string      `json:"name"`
+	Children []*treeNode `json:"children"`
+}
+
+func openapiAuth(c *web.Context)   {}
+func openapiCheck(c *web.Context)  {}
+func openapiCreate(c *web.Context) {}
+
+func newOpenAPIHarness() *webtest.Harness {
+	h := webtest.New()
+	role := web.NewRole("user")
+	role.UseBefore(1, openapiAuth)
+	g := web.NewGroup("user", "order")
+	g.UseBefore(openapiCheck, "create")
+	g.BindAction("create", openapiCreate, web.WithSummary("创建订单"), web.WithSchema(reqCreateOrder{}, respCreateOrder{}))
+	g.BindAction("list", openapiCreate)
+	h.UseRole(role)
+	h.UseGroup(g)
+	return h
+}
+
+func TestDescribeAndHandlerChain(t *testing.T) {
+	h := newOpenAPIHarness()
+	roles := h.Describe()
+	if len(roles) != 1 || roles[0].Label != "user" || len(roles[0].Groups) != 1 {
+		t.Fatalf("describe: %+v", roles)
+	}
+	actions := roles[0].Groups[0].Actions
+	if len(actions) != 2 || actions[0].Label != "create" || actions[1].Label != "list" {
+		t.Fatalf("actions: %+v", actions)
+	}
+	if actions[0].Summary != "创建订单" || len(actions[0].Before) != 1 || !strings.HasSuffix(actions[0].Before[0], "openapiCheck") {
+		t.Errorf("create: %+v", actions[0])
+	}
+	if len(actions[1].Before) != 0 {
+		t.Errorf("list before: %v", actions[1].Before)
+	}
+
+	chain, ok := h.HandlerChain("user", "order", "create")
+	if !ok {
+		t.Fatal("chain not found")
+	}
+	levels := make([]string, 0)
+	for _, entry := range chain {
+		levels = append(levels, entry.Level+":"+entry.Name[strings.LastIndex(entry.Name, ".")+1:])
+	}
+	expect := []string{"role:openapiAuth", "group:openapiCheck", "action:openapiCreate"}
+	if !reflect.DeepEqual(levels, expect) {
+		t.Errorf("chain %v, expect %v", levels, expect)
+	}
+	if _, ok = h.HandlerChain("user", "order", "missing"); ok {
+		t.Error("missing action should not have a chain")
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	h := newOpenAPIHarness()
+	doc := h.OpenAPI(web.OpenAPIOption{Title: "shop"})
+	if doc.Info.Title != "shop" || len(doc.Paths) != 2 {
+		t.Fatalf("doc: %+v", doc)
+	}
+	op, ok := doc.Paths["/user/order/create"]["post"]
+	if !ok {
+		t.Fatalf("paths: %v", doc.Paths)
+	}
+	if op.OperationId != "user.order.create" || op.Summary != "创建订单" {
+		t.Errorf("operation: %+v", op)
+	}
+
+	req := op.RequestBody.Content["application/json"].Schema
+	if req.Properties["title"].Type != "string" || req.Properties["count"].Format != "int64" {
+		t.Errorf("request properties: %+v", req.Properties)
+	}
+	if !reflect.DeepEqual(req.Required, []string{"title"}) {
+		t.Errorf("required: %v", req.Required)
+	}
+
+	resp := op.Responses["200"].Content["application/json"].Schema
+	data := resp.Properties["data"]
+	if resp.Properties["err_code"] == nil || data == nil {
+		t.Fatalf("response: %+v", resp.Properties)
+	}
+	if data.Properties["created"].Format != "date-time" || data.Properties["tags"].Items.Type != "string" {
+		t.Errorf("response data: %+v", data.Properties)
+	}
+
+	list := doc.Paths["/user/order/list"]["post"]
+	if list.RequestBody != nil || list.Responses["200"].Content["application/json"].Schema.Properties["data"] != nil {
+		t.Errorf("list without schema: %+v", list)
+	}
+}
+
+func TestSchemaRecursiveType(t *testing.T) {
+	s := web.SchemaOf(reflect.TypeOf(treeNode{}))
+	if s.Properties["name"].Type != "string" {
+		t.Fatalf("schema: %+v", s.Properties)
+	}
+	children := s.Properties["children"]
+	if children.Type != "array" || children.Items.Type != "object" || children.Items.Properties != nil {
+		t.Errorf("recursive children: %+v", children.Items)
+	}
+}
+
+func TestServeSwaggerUI(t *testing.T) {
+	h := newOpenAPIHarness()
+	h.ServeOpenAPI("/openapi.json", web.OpenAPIOption{})
+	h.ServeSwaggerUI("/docs", "/openapi.json")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	doc := web.OpenAPIDoc{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || doc.Paths["/user/order/create"] == nil {
+		t.Fatalf("openapi.json: %v %s", err, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	page := w.Body.String()
+	if !strings.Contains(page, `url: "/openapi.json"`) {
+		t.Errorf("doc path missing: %s", page)
+	}
+	// 外部资源固定版本并带有 integrity
+	for _, tag := range []string{"<link", "<script src"} {
+		i := strings.Index(page, tag)
+		if i < 0 {
+			t.Fatalf("%s missing", tag)
+		}
+		el := page[i : i+strings.Index(page[i:], ">")]
+		if !strings.Contains(el, "swagger-ui-dist@5.18.2/") || !strings.Contains(el, `integrity="sha384-`) {
+			t.Errorf("unpinned resource: %s", el)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/limits"
+	"catuan/web"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	comm.SetClock(func() time.Time { return now })
+	defer comm.SetClock(nil)
+
+	ctx := context.Background()
+	tb := limits.NewTokenBucket(2, time.Second)
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := tb.Allow(ctx, "ip"); !ok {
+			t.Fatalf("request %d should pass", i)
+		}
+	}
+	ok, wait, _ := tb.Allow(ctx, "ip")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("bucket empty: %v %v", ok, wait)
+	}
+	if ok, _, _ := tb.Allow(ctx, "other"); !ok {
+		t.Error("keys should not share tokens")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _, _ := tb.Allow(ctx, "ip"); !ok {
+		t.Error("a token should be refilled")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	comm.SetClock(func() time.Time { return now })
+	defer comm.SetClock(nil)
+
+	ctx := context.Background()
+	sw := limits.NewSlidingWindow(2, time.Minute)
+	sw.Allow(ctx, "ip")
+	now = now.Add(20 * time.Second)
+	sw.Allow(ctx, "ip")
+	ok, wait, _ := sw.Allow(ctx, "ip")
+	if ok || wait != 40*time.Second {
+		t.Errorf("window full: %v %v", ok, wait)
+	}
+	now = now.Add(40 * time.Second)
+	if ok, _, _ := sw.Allow(ctx, "ip"); !ok {
+		t.Error("oldest request should leave the window")
+	}
+	if ok, _, _ := sw.Allow(ctx, "ip"); ok {
+		t.Error("second request is still inside the window")
+	}
+}
+
+func TestRateLimitConfigValidation(t *testing.T) {
+	for conf, want := range map[string]string{
+		"rateLimit:\n  - name: api\n    algorithm: leakyBucket\n    limit: 10\n    window: 1\n": "leakyBucket",
+		"rateLimit:\n  - name: api\n    by: [ip, tenant]\n    limit: 10\n    window: 1\n":       "tenant",
+		"rateLimit:\n  - name: api\n    limit: 0\n    window: 1\n":                              "positive",
+		"rateLimit:\n  - name: api\n    limit: 10\n    window: -1\n":                            "positive",
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "application.yaml"), []byte(conf), 0644); err != nil {
+			t.Fatal(err)
+		}
+		func() {
+			defer func() {
+				r := recover()
+				if msg, _ := r.(string); !strings.Contains(msg, want) {
+					t.Errorf("%q should fail at config load: %v", want, r)
+				}
+			}()
+			web.New("test", dir)
+		}()
+	}
+}
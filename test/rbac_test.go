@@ -0,0 +1,76 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/auth"
+	"catuan/web"
+	"catuan/web/webtest"
+	"context"
+	"reflect"
+	"testing"
+)
+
+type staticPermissions map[string][]string
+
+func (p staticPermissions) Roles(ctx context.Context, userID string) ([]string, error) {
+	return nil, nil
+}
+
+func (p staticPermissions) Permissions(ctx context.Context, userID string) ([]string, error) {
+	return p[userID], nil
+}
+
+func TestActionsByPermission(t *testing.T) {
+	h := webtest.New()
+	g := web.NewGroup("admin", "order")
+	g.BindAction("list", func(c *web.Context) {}, web.WithPermissions("order:read"))
+	g.BindAction("refund", func(c *web.Context) {}, web.WithPermissions("order:read", "order:refund"))
+	g.BindAction("ping", func(c *web.Context) {})
+	h.UseRole(web.NewRole("admin"))
+	h.UseGroup(g)
+
+	names := func(refs []web.ActionRef) []string {
+		out := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			out = append(out, ref.Action)
+		}
+		return out
+	}
+	if got := names(h.ActionsByPermission("order:read")); !reflect.DeepEqual(got, []string{"list", "refund"}) {
+		t.Errorf("order:read: %v", got)
+	}
+	if got := names(h.ActionsByPermission("order:refund")); !reflect.DeepEqual(got, []string{"refund"}) {
+		t.Errorf("order:refund: %v", got)
+	}
+	if got := names(h.ActionsByPermission("order:*")); !reflect.DeepEqual(got, []string{"list", "refund"}) {
+		t.Errorf("order:*: %v", got)
+	}
+	if got := names(h.UnguardedActions()); !reflect.DeepEqual(got, []string{"list", "refund"}) {
+		t.Errorf("unguarded: %v", got)
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	h := webtest.New()
+	h.SetRBAC(staticPermissions{"1": {"order:read"}, "2": {"order:*"}})
+	role := web.NewRole("admin")
+	role.RequirePermission(nil)
+	g := web.NewGroup("admin", "order")
+	g.BindAction("refund", func(c *web.Context) {
+		c.Result(comm.ErrCodeSuccess, "success", nil)
+	}, web.WithPermissions("order:read", "order:refund"))
+	h.UseRole(role)
+	h.UseGroup(g)
+
+	if refs := h.UnguardedActions(); len(refs) != 0 {
+		t.Errorf("role guard not detected: %v", refs)
+	}
+	h.AuthAs(&auth.Claims{Subject: "1"})
+	if res := h.Invoke("admin", "order", "refund", nil, nil); res.ErrCode != comm.ErrCodeForbidden {
+		t.Errorf("missing order:refund: %s", res.Body)
+	}
+	h.AuthAs(&auth.Claims{Subject: "2"})
+	if res := h.Invoke("admin", "order", "refund", nil, nil); res.ErrCode != comm.ErrCodeSuccess {
+		t.Errorf("order:* should pass: %s", res.Body)
+	}
+}
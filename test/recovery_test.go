@@ -0,0 +1,146 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/reporters"
+	"catuan/web"
+	"catuan/web/webtest"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func panicOrder(c *web.Context) {
+	panic("order panic")
+}
+
+// newPanicHarness action 直接 panic, 上报到 webhook/sentry/文件
+func newPanicHarness(t *testing.T) (*webtest.Harness, chan map[string]any, chan map[string]any, string) {
+	webhooks := make(chan map[string]any, 1)
+	sentries := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		payload := make(map[string]any)
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			t.Errorf("payload %s: %v", raw, err)
+		}
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			if !strings.Contains(r.Header.Get("X-Sentry-Auth"), "sentry_key=pub") {
+				t.Errorf("sentry auth %q", r.Header.Get("X-Sentry-Auth"))
+			}
+			sentries <- payload
+		} else {
+			webhooks <- payload
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	sentry, err := reporters.NewSentryReporter(strings.Replace(srv.URL, "://", "://pub@", 1)+"/42", "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "panic.log")
+	fileReporter, err := reporters.NewFileReporter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		fileReporter.Close()
+	})
+
+	h := webtest.New()
+	h.UseReporter(reporters.NewWebhookReporter(srv.URL+"/hook", nil), sentry, fileReporter)
+	g := web.NewGroup("user", "order")
+	g.BindAction("pay", panicOrder, web.WithTimeout(time.Minute))
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+	return h, webhooks, sentries, file
+}
+
+func TestPanicRespondsImmediately(t *testing.T) {
+	h, webhooks, sentries, _ := newPanicHarness(t)
+	// 使用协程处理, 与线上相同
+	h.SyncDispatch(false)
+	start := time.Now()
+	res := h.Invoke("user", "order", "pay", nil, nil)
+	if time.Since(start) > time.Second {
+		t.Errorf("panic response took %s", time.Since(start))
+	}
+	if res.Status != http.StatusInternalServerError || res.ErrCode != comm.ErrCodeFail {
+		t.Errorf("response: %d %s", res.Status, res.Body)
+	}
+	<-webhooks
+	<-sentries
+}
+
+func TestPanicReportPayloads(t *testing.T) {
+	h, webhooks, sentries, file := newPanicHarness(t)
+	res := h.Invoke("user", "order", "pay?access_token=t1&id=7", nil, map[string]string{web.RequestIDHeader: "req-1"})
+	if res.ErrCode != comm.ErrCodeFail {
+		t.Fatalf("response: %s", res.Body)
+	}
+
+	var hook map[string]any
+	select {
+	case hook = <-webhooks:
+	case <-time.After(time.Second * 5):
+		t.Fatal("webhook not reported")
+	}
+	for key, want := range map[string]string{
+		"message": "order panic", "request_id": "req-1", "role": "user", "group": "order", "action": "pay", "method": http.MethodPost,
+		"url": "/user/order/pay?access_token=***&id=7",
+	} {
+		if hook[key] != want {
+			t.Errorf("webhook %s = %v, want %s", key, hook[key], want)
+		}
+	}
+	frames, _ := hook["frames"].([]any)
+	if len(frames) == 0 || !strings.HasSuffix(frames[0].(map[string]any)["function"].(string), "panicOrder") {
+		t.Errorf("first frame should be the panic site: %v", frames)
+	}
+	if stack, _ := hook["stack"].(string); !strings.Contains(stack, "panicOrder") {
+		t.Error("stack missing")
+	}
+
+	var sentry map[string]any
+	select {
+	case sentry = <-sentries:
+	case <-time.After(time.Second * 5):
+		t.Fatal("sentry not reported")
+	}
+	if sentry["message"] != "order panic" || sentry["transaction"] != "user/order/pay" || sentry["environment"] != "test" {
+		t.Errorf("sentry payload: %v", sentry)
+	}
+	if tags, _ := sentry["tags"].(map[string]any); tags["request_id"] != "req-1" {
+		t.Errorf("sentry tags: %v", sentry["tags"])
+	}
+	exception := sentry["exception"].(map[string]any)["values"].([]any)[0].(map[string]any)
+	sentryFrames := exception["stacktrace"].(map[string]any)["frames"].([]any)
+	last := sentryFrames[len(sentryFrames)-1].(map[string]any)
+	if !strings.HasSuffix(last["function"].(string), "panicOrder") {
+		t.Errorf("sentry last frame should be the panic site: %v", last)
+	}
+
+	// 按 webhook/sentry/文件 依次上报, 收到 sentry 请求时文件可能尚未写入
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		raw, _ := os.ReadFile(file)
+		if len(raw) > 0 {
+			line := make(map[string]any)
+			if err := json.Unmarshal(raw, &line); err != nil || line["request_id"] != "req-1" {
+				t.Errorf("file report %s: %v", raw, err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("file not reported")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/sessions"
+	"catuan/web"
+	"catuan/web/webtest"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSessionExpiryAndTouch(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	comm.SetClock(func() time.Time { return now })
+	defer comm.SetClock(nil)
+
+	ctx := context.Background()
+	store := sessions.NewMemoryStore()
+	m := sessions.NewManager(store, sessions.Options{Expires: time.Minute})
+	s := m.Load(ctx, "")
+	s.Set("cart", "1")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	id := s.ID()
+
+	// 访问后重新计算过期时间
+	now = now.Add(50 * time.Second)
+	if err := m.Load(ctx, id).Save(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(50 * time.Second)
+	loaded := m.Load(ctx, id)
+	if loaded.IsNew() {
+		t.Fatal("touched session should still be valid")
+	}
+	if v, _ := loaded.Get("cart"); v != "1" {
+		t.Errorf("value: %s", v)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !m.Load(ctx, id).IsNew() {
+		t.Error("session should expire")
+	}
+}
+
+func TestMemoryStoreTouchExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	comm.SetClock(func() time.Time { return now })
+	defer comm.SetClock(nil)
+
+	ctx := context.Background()
+	store := sessions.NewMemoryStore()
+	if err := store.Save(ctx, "s1", &sessions.Data{Values: map[string]string{}}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if err := store.Touch(ctx, "s1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := store.Get(ctx, "s1"); data != nil {
+		t.Error("touch should not revive an expired session")
+	}
+}
+
+func TestSessionMaxPerUser(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	comm.SetClock(func() time.Time { return now })
+	defer comm.SetClock(nil)
+
+	ctx := context.Background()
+	m := sessions.NewManager(sessions.NewMemoryStore(), sessions.Options{MaxPerUser: 2})
+	ids := make([]string, 0)
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Second)
+		s := m.Load(ctx, "")
+		if err := s.Login("1001"); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.ID())
+	}
+	if !m.Load(ctx, ids[0]).IsNew() {
+		t.Error("oldest session should be evicted")
+	}
+	for _, id := range ids[1:] {
+		if s := m.Load(ctx, id); s.IsNew() || s.UserID() != "1001" {
+			t.Errorf("session %s should remain", id)
+		}
+	}
+}
+
+func TestSessionSavedOnDispatcher(t *testing.T) {
+	store := &countingStore{MemoryStore: sessions.NewMemoryStore()}
+	m := sessions.NewManager(store, sessions.Options{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	g := web.NewGroup("user", "cart")
+	g.BindAction("add", func(c *web.Context) {
+		s, _ := c.Session()
+		s.Set("cart", "1")
+		c.Result(comm.ErrCodeSuccess, "")
+	})
+	g.BindAction("slow", func(c *web.Context) {
+		defer close(done)
+		s, _ := c.Session()
+		s.Set("cart", "2")
+		<-release
+		c.Result(comm.ErrCodeSuccess, "")
+	}, web.WithTimeout(time.Millisecond*50))
+
+	h := webtest.New()
+	defer h.Close()
+	h.SyncDispatch(false)
+	h.UseSession(m)
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+
+	res := h.Invoke("user", "cart", "add", nil, nil)
+	id := res.Header.Get(m.Header())
+	if id == "" {
+		t.Fatal("new session id should be returned")
+	}
+	if v, _ := m.Load(context.Background(), id).Get("cart"); v != "1" {
+		t.Errorf("saved value: %q", v)
+	}
+
+	// 超时后处理协程不再保存会话和写响应头
+	res = h.Invoke("user", "cart", "slow", nil, nil)
+	close(release)
+	<-done
+	if res.ErrCode != comm.ErrCodeTimeout || res.Header.Get(m.Header()) != "" {
+		t.Errorf("timeout response: %d %v", res.ErrCode, res.Header)
+	}
+	time.Sleep(time.Millisecond * 20)
+	if n := atomic.LoadInt32(&store.saves); n != 1 {
+		t.Errorf("timed out session should not be saved, saves %d", n)
+	}
+}
+
+// countingStore 记录保存次数
+type countingStore struct {
+	*sessions.MemoryStore
+	saves int32
+}
+
+func (s *countingStore) Save(ctx context.Context, id string, data *sessions.Data, ttl time.Duration) error {
+	atomic.AddInt32(&s.saves, 1)
+	return s.MemoryStore.Save(ctx, id, data, ttl)
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/web"
+	"catuan/web/webtest"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newStreamHarness(h web.StreamHandlerFunc) *webtest.Harness {
+	harness := webtest.New()
+	g := web.NewGroup("user", "report")
+	g.BindStream("export", h)
+	harness.UseRole(web.NewRole("user"))
+	harness.UseGroup(g)
+	return harness
+}
+
+func TestStreamSSEFraming(t *testing.T) {
+	h := newStreamHarness(func(c *web.Context, s *web.Stream) error {
+		_ = s.Send("line1\nline2\r\nline3\rline4")
+		_ = s.SendEvent("progress", map[string]int{"done": 1})
+		_ = s.SendEvent("bad\nevent", "x")
+		_ = s.Ping()
+		return comm.ErrNotFound
+	})
+	res := h.Invoke("user", "report", "export", nil, map[string]string{"Accept": "text/event-stream"})
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type %s", ct)
+	}
+	expect := "data: line1\ndata: line2\ndata: line3\ndata: line4\n\n" +
+		"event: progress\ndata: {\"done\":1}\n\n" +
+		"event: bad event\ndata: x\n\n" +
+		": ping\n\n" +
+		"event: error\ndata: {\"err_code\":404,"
+	if body := string(res.Body); !strings.HasPrefix(body, expect) || !strings.HasSuffix(body, "}\n\n") {
+		t.Errorf("sse body:\n%q", body)
+	}
+}
+
+func TestStreamNDJSONFraming(t *testing.T) {
+	h := newStreamHarness(func(c *web.Context, s *web.Stream) error {
+		if s.Mode() != web.StreamNDJSON {
+			t.Errorf("mode %s", s.Mode())
+		}
+		_ = s.Send(map[string]int{"row": 1})
+		_ = s.Send("multi\nline")
+		_ = s.SendEvent("progress", 50)
+		_ = s.Ping()
+		return nil
+	})
+	res := h.Invoke("user", "report", "export", nil, map[string]string{"Accept": "application/x-ndjson"})
+	if ct := res.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("content type %s", ct)
+	}
+	expect := "{\"row\":1}\n\"multi\\nline\"\n{\"data\":50,\"event\":\"progress\"}\n\n"
+	if body := string(res.Body); body != expect {
+		t.Errorf("ndjson body:\n%q", body)
+	}
+}
+
+func TestStreamErrorBeforeStart(t *testing.T) {
+	h := newStreamHarness(func(c *web.Context, s *web.Stream) error {
+		return comm.ErrForbidden.Wrap(errors.New("no export permission"))
+	})
+	res := h.Invoke("user", "report", "export", nil, map[string]string{"Accept": "text/event-stream"})
+	if res.Status != http.StatusForbidden || res.ErrCode != comm.ErrCodeForbidden {
+		t.Errorf("error before start should be a normal response: %d %s", res.Status, res.Body)
+	}
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		t.Error("stream headers should not be sent")
+	}
+}
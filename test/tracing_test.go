@@ -0,0 +1,55 @@
+package test
+
+import (
+	"catuan/components/tracing"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTraceparent(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := tracing.ParseTraceparent(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Sampled() || sc.Traceparent() != header {
+		t.Errorf("round trip: %s", sc.Traceparent())
+	}
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		header + "-extra",
+	} {
+		if _, err := tracing.ParseTraceparent(bad); err == nil {
+			t.Errorf("%q should be invalid", bad)
+		}
+	}
+}
+
+func TestTracerPropagation(t *testing.T) {
+	tracer := tracing.NewTracer(tracing.Options{})
+	h := http.Header{}
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	remote, ok := tracing.Extract(h)
+	if !ok {
+		t.Fatal("extract failed")
+	}
+	ctx, root := tracer.Start(tracing.ContextWithRemoteSpanContext(context.Background(), remote), "root")
+	ctx, child := tracing.StartSpan(ctx, "child")
+	if child == nil || child.TraceID() != root.TraceID() || root.TraceID() != remote.TraceID.String() {
+		t.Fatal("child should share the upstream trace id")
+	}
+	out := http.Header{}
+	tracing.Inject(ctx, out)
+	if !strings.HasSuffix(out.Get("traceparent"), child.SpanContext().SpanID.String()+"-01") {
+		t.Errorf("inject: %s", out.Get("traceparent"))
+	}
+	if _, span := tracing.StartSpan(context.Background(), "orphan"); span != nil {
+		t.Error("no span should be created without a parent")
+	}
+}
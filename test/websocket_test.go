@@ -0,0 +1,96 @@
+package test
+
+import (
+	"catuan/components/websockets"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newWebSocketServer(t *testing.T, opt websockets.Options) (*websockets.Hub, *websocket.Conn) {
+	hub := websockets.NewHub(opt)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.Upgrade(w, r, "u1", nil)
+	}))
+	t.Cleanup(func() {
+		hub.Close()
+		srv.Close()
+	})
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ws.Close()
+	})
+	deadline := time.Now().Add(time.Second)
+	for hub.Online("u1") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return hub, ws
+}
+
+func TestWebSocketPongKeepsAlive(t *testing.T) {
+	hub, ws := newWebSocketServer(t, websockets.Options{
+		PingInterval: time.Millisecond * 20,
+		ReadTimeout:  time.Millisecond * 80,
+	})
+	// 客户端只读取, 读取时自动回复 pong, 不发送任何数据帧
+	received := make(chan string, 1)
+	go func() {
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err == nil {
+			received <- msg
+		}
+	}()
+	time.Sleep(time.Millisecond * 300)
+	if hub.Online("u1") != 1 {
+		t.Fatal("connection answering pings should stay open")
+	}
+	if err := hub.SendToUser("u1", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-received:
+		if msg != "hello" {
+			t.Errorf("message %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestWebSocketReadTimeout(t *testing.T) {
+	hub, _ := newWebSocketServer(t, websockets.Options{
+		PingInterval: time.Millisecond * 20,
+		ReadTimeout:  time.Millisecond * 80,
+	})
+	// 客户端不读取也就不回复 pong, 超时后断开
+	deadline := time.Now().Add(time.Second)
+	for hub.Online("u1") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("silent connection should be closed after ReadTimeout")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func TestWebSocketClientPing(t *testing.T) {
+	_, ws := newWebSocketServer(t, websockets.Options{ReadTimeout: time.Millisecond * 200})
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Millisecond * 100)
+		if err := websocket.Message.Send(ws, "ping"); err != nil {
+			t.Fatal(err)
+		}
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err != nil || msg != "pong" {
+			t.Fatalf("reply %q: %v", msg, err)
+		}
+	}
+}
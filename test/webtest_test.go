@@ -0,0 +1,62 @@
+package test
+
+import (
+	"catuan/comm"
+	"catuan/components/auth"
+	"catuan/web"
+	"catuan/web/webtest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func requireVip(c *web.Context) {
+	if c.Claims() == nil || c.Claims().Subject != "vip" {
+		c.Fail(comm.ErrForbidden)
+	}
+}
+
+func TestWebtestInvoke(t *testing.T) {
+	h := webtest.New()
+	defer h.Close()
+	type reqEcho struct {
+		Name string `json:"name" binding:"required"`
+	}
+	type respEcho struct {
+		Name string `json:"name"`
+		Now  int64  `json:"now"`
+	}
+	g := web.NewGroup("user", "echo")
+	g.UseBefore(requireVip)
+	web.BindTypedAction(g, "say", func(c *web.Context, req *reqEcho) (respEcho, error) {
+		return respEcho{Name: req.Name, Now: comm.Now().Unix()}, nil
+	})
+	h.UseRole(web.NewRole("user"))
+	h.UseGroup(g)
+
+	res := h.Invoke("user", "echo", "say", map[string]string{"name": "cat"}, nil)
+	if res.Status != http.StatusForbidden || res.ErrCode != comm.ErrCodeForbidden {
+		t.Fatalf("anonymous: %d %s", res.Status, res.Body)
+	}
+	res.AssertBeforeRan(t, "test.requireVip")
+
+	h.AuthAs(&auth.Claims{Subject: "vip", Role: "user"})
+	clock := h.FreezeTime(time.Unix(1700000000, 0))
+	clock.Advance(time.Minute)
+	res = h.Invoke("user", "echo", "say", map[string]string{"name": "cat"}, map[string]string{"Accept-Language": "en"})
+	data := respEcho{}
+	if err := res.Decode(&data); err != nil || res.ErrCode != comm.ErrCodeSuccess {
+		t.Fatalf("vip: %v %s", err, res.Body)
+	}
+	if data.Name != "cat" || data.Now != 1700000060 {
+		t.Errorf("data: %+v", data)
+	}
+	if len(res.Header.Get(web.RequestIDHeader)) == 0 {
+		t.Error("missing request id header")
+	}
+
+	res = h.Invoke("user", "echo", "say", nil, map[string]string{"Accept-Language": "en"})
+	if res.ErrCode != comm.ErrCodeBadRequest || res.Status != http.StatusBadRequest {
+		t.Errorf("validation: %d %s", res.Status, res.Body)
+	}
+}
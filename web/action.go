@@ -0,0 +1,108 @@
+package web
+
+import (
+	"catuan/comm"
+	"reflect"
+	"time"
+)
+
+// ActionMeta action 描述信息, 接口文档等功能依赖此信息
+type ActionMeta struct {
+	Name     string
+	Summary  string
+	Request  reflect.Type // 请求参数类型, 未声明时为 nil
+	Response reflect.Type // 响应 data 类型, 未声明时为 nil
+
+	Permissions []string // 访问所需的权限, 需全部满足
+	Encoding    string   // 固定的响应编码, 为空时根据 Accept 协商
+
+	Stream    bool          // 流式 action, 由 BindStream 设置
+	WebSocket bool          // websocket action, 由 BindWebSocket 设置
+	Upload    bool          // 上传 action, 由 BindUpload 设置
+	Download  bool          // 下载 action, 由 BindDownload 设置
+	Timeout   time.Duration // 处理超时时间, 为 0 时普通 action 使用默认 5 秒, 流式/websocket/下载 action 不限制
+}
+
+type ActionOption func(meta *ActionMeta)
+
+// WithSummary 设置 action 说明
+func WithSummary(summary string) ActionOption {
+	return func(meta *ActionMeta) {
+		meta.Summary = summary
+	}
+}
+
+// WithSchema 声明请求/响应类型, 传入对应类型的零值即可, 例如 WithSchema(ReqLogin{}, RespLogin{})
+func WithSchema(req, resp any) ActionOption {
+	return func(meta *ActionMeta) {
+		if req != nil {
+			meta.Request = reflect.TypeOf(req)
+		}
+		if resp != nil {
+			meta.Response = reflect.TypeOf(resp)
+		}
+	}
+}
+
+// WithPermissions 声明访问 action 所需的权限, 由 RequirePermission 校验
+func WithPermissions(perms ...string) ActionOption {
+	return func(meta *ActionMeta) {
+		meta.Permissions = append(meta.Permissions, perms...)
+	}
+}
+
+// WithEncoding 指定响应编码, 忽略请求的 Accept, 例如 WithEncoding(EncodingXML)
+func WithEncoding(name string) ActionOption {
+	return func(meta *ActionMeta) {
+		meta.Encoding = name
+	}
+}
+
+// WithTimeout 设置 action 处理超时时间
+func WithTimeout(timeout time.Duration) ActionOption {
+	return func(meta *ActionMeta) {
+		meta.Timeout = timeout
+	}
+}
+
+func newActionMeta(action string, opts ...ActionOption) *ActionMeta {
+	meta := &ActionMeta{Name: action}
+	for _, opt := range opts {
+		opt(meta)
+	}
+	return meta
+}
+
+type TypedHandlerFunc[Req any, Resp any] func(c *Context, req *Req) (Resp, error)
+
+// BindTypedAction 绑定带类型的 action, 请求参数自动解析, 返回值作为 data 响应
+func BindTypedAction[Req any, Resp any](g GroupInf, action string, h TypedHandlerFunc[Req, Resp], opts ...ActionOption) {
+	var req Req
+	var resp Resp
+	opts = append([]ActionOption{WithSchema(req, resp)}, opts...)
+	g.BindAction(action, func(c *Context) {
+		reqInfo := new(Req)
+		if err := c.ShouldBind(reqInfo); err != nil {
+			c.Fail(c.ValidationError(err))
+			return
+		}
+		data, err := h(c, reqInfo)
+		if err != nil {
+			c.Fail(err)
+			return
+		}
+		c.Result(comm.ErrCodeSuccess, "success", data)
+	}, opts...)
+}
+
+// ActionMeta 当前请求 action 的描述信息
+func (c *Context) ActionMeta() (*ActionMeta, bool) {
+	if c.app == nil {
+		return nil, false
+	}
+	group, ok := c.app.FindGroup(c.RoleLabel(), c.GroupLabel())
+	if !ok {
+		return nil, false
+	}
+	return group.FindActionMeta(c.ActionLabel())
+}
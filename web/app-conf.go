@@ -1,13 +1,28 @@
 package web
 
+import (
+	"fmt"
+)
+
 type AppConfInfo struct {
-	Version   string            `yaml:"version"`
-	ActiveEnv string            `yaml:"activeEnv"`
-	Web       *WebConfInfo      `yaml:"web"`
-	Mysql     *MysqlConfInfo    `yaml:"mysql"`
-	Redis     []*RedisConfInfo  `yaml:"redis"`
-	Log       *LogConfInfo      `yaml:"log"`
-	Env       map[string]string `yaml:"env"`
+	Version   string             `yaml:"version"`
+	ActiveEnv string             `yaml:"activeEnv"`
+	Web       *WebConfInfo       `yaml:"web"`
+	Mysql     *MysqlConfInfo     `yaml:"mysql"`
+	Redis     []*RedisConfInfo   `yaml:"redis"`
+	Log       *LogConfInfo       `yaml:"log"`
+	Jwt       *JwtConfInfo       `yaml:"jwt"`
+	Session   *SessionConfInfo   `yaml:"session"`
+	RateLimit []*RateLimitInfo   `yaml:"rateLimit"`
+	WebSocket *WebSocketConfInfo `yaml:"webSocket"`
+	Storage   *StorageConfInfo   `yaml:"storage"`
+	I18n      *I18nConfInfo      `yaml:"i18n"`
+	Report    *ReportConfInfo    `yaml:"report"`
+	Metrics   *MetricsConfInfo   `yaml:"metrics"`
+	Tracing   *TracingConfInfo   `yaml:"tracing"`
+
+	HttpClients map[string]*HttpClientConfInfo `yaml:"httpClients"`
+	Env         map[string]string              `yaml:"env"`
 }
 
 type WebConfInfo struct {
@@ -48,3 +63,143 @@ type LogConfInfo struct {
 	Level    string `yaml:"level"`    //级别
 	FilePath string `yaml:"filePath"` //文件路径
 }
+
+type JwtConfInfo struct {
+	Issuer    string        `yaml:"issuer"`
+	Audience  string        `yaml:"audience"`  // 不为空时校验 token 的 aud
+	Expires   int           `yaml:"expires"`   // 有效期 秒
+	ActiveKid string        `yaml:"activeKid"` // 签发使用的密钥, 其余密钥仅用于验证
+	Keys      []*JwtKeyInfo `yaml:"keys"`
+}
+
+type JwtKeyInfo struct {
+	Kid            string `yaml:"kid"`
+	Alg            string `yaml:"alg"` // HS256 / RS256, 其他值加载配置时报错
+	Secret         string `yaml:"secret"`
+	PrivateKeyFile string `yaml:"privateKeyFile"`
+	PublicKeyFile  string `yaml:"publicKeyFile"`
+}
+
+type SessionConfInfo struct {
+	Header     string `yaml:"header"`     // 会话 ID 请求头
+	Expires    int    `yaml:"expires"`    // 过期时间 秒, 访问后重新计算
+	MaxPerUser int    `yaml:"maxPerUser"` // 单用户同时有效的会话数量
+	Redis      int    `yaml:"redis"`      // 使用的 redis 序号
+	Prefix     string `yaml:"prefix"`     // redis key 前缀
+}
+
+type WebSocketConfInfo struct {
+	PingInterval int    `yaml:"pingInterval"` // ping 间隔 秒
+	ReadTimeout  int    `yaml:"readTimeout"`  // 未收到客户端任何帧(包括 pong)的超时时间 秒, 0 为不限制
+	SendBuffer   int    `yaml:"sendBuffer"`   // 每个连接的发送缓冲
+	Fanout       bool   `yaml:"fanout"`       // 是否通过 redis pub/sub 在实例间转发, 默认不转发
+	Redis        int    `yaml:"redis"`        // 用于实例间转发的 redis 序号
+	Channel      string `yaml:"channel"`      // pub/sub 频道
+}
+
+// StorageConfInfo 文件存储配置, type 为 local 或 s3/cos
+type StorageConfInfo struct {
+	Type string `yaml:"type"`
+	// 本地存储
+	Root      string `yaml:"root"`      // 存储目录
+	BaseURL   string `yaml:"baseURL"`   // 签名下载地址前缀, 例如 https://api.example.com/files
+	ServePath string `yaml:"servePath"` // 下载路由, 例如 /files
+	Secret    string `yaml:"secret"`    // 签名密钥
+	// S3 兼容存储
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"accessKey"`
+	SecretKey string `yaml:"secretKey"`
+	PathStyle bool   `yaml:"pathStyle"`
+}
+
+// I18nConfInfo 多语言配置, dir 下每个语言一个 yaml/json 文件, 文件名为语言, 例如 zh-CN.yaml
+type I18nConfInfo struct {
+	Dir     string `yaml:"dir"`
+	Default string `yaml:"default"` // 默认语言, 默认 zh-CN
+}
+
+// ReportConfInfo panic 上报配置
+type ReportConfInfo struct {
+	Webhook   string `yaml:"webhook"`   // 以 json POST 到该地址
+	File      string `yaml:"file"`      // 每个事件一行 json 追加到该文件
+	SentryDSN string `yaml:"sentryDSN"` // sentry 或兼容服务的 dsn
+}
+
+// MetricsConfInfo 监控指标配置
+type MetricsConfInfo struct {
+	Path string `yaml:"path"` // prometheus 拉取地址, 默认 /metrics
+}
+
+// TracingConfInfo 链路追踪配置, endpoint 为空且未开启 stdout 时只透传 traceparent
+type TracingConfInfo struct {
+	Service     string            `yaml:"service"`     // 服务名, 默认 catuan
+	Endpoint    string            `yaml:"endpoint"`    // otlp/http 地址, 例如 http://collector:4318
+	Headers     map[string]string `yaml:"headers"`     // 发送到 collector 的请求头
+	Stdout      bool              `yaml:"stdout"`      // 输出到标准输出, 用于本地调试
+	SampleRatio float64           `yaml:"sampleRatio"` // 采样比例, 0 为全部采样
+}
+
+type RateLimitInfo struct {
+	Name      string   `yaml:"name"`
+	Role      string   `yaml:"role"`      // 为空匹配全部
+	Group     string   `yaml:"group"`     // 为空匹配全部
+	Action    string   `yaml:"action"`    // 为空匹配全部
+	By        []string `yaml:"by"`        // 限流维度 role/group/action/ip/user
+	Algorithm string   `yaml:"algorithm"` // tokenBucket / slidingWindow, 默认 tokenBucket
+	Limit     int      `yaml:"limit"`     // 窗口内允许的请求数
+	Window    int      `yaml:"window"`    // 时间窗口 秒
+	Backend   string   `yaml:"backend"`   // local / redis, 默认 local
+	Redis     int      `yaml:"redis"`     // 使用的 redis 序号
+}
+
+// HttpClientConfInfo 对外请求 client 配置, 时间单位均为毫秒
+type HttpClientConfInfo struct {
+	Timeout             int              `yaml:"timeout"`
+	HostTimeouts        map[string]int   `yaml:"hostTimeouts"`
+	Proxy               string           `yaml:"proxy"`
+	MaxIdleConns        int              `yaml:"maxIdleConns"`
+	MaxIdleConnsPerHost int              `yaml:"maxIdleConnsPerHost"`
+	MaxConnsPerHost     int              `yaml:"maxConnsPerHost"`
+	IdleConnTimeout     int              `yaml:"idleConnTimeout"`
+	Retry               *HttpRetryInfo   `yaml:"retry"`
+	Breaker             *HttpBreakerInfo `yaml:"breaker"`
+	Log                 *HttpLogInfo     `yaml:"log"`
+}
+
+// HttpLogInfo 请求日志配置, Redact 为额外脱敏的字段
+type HttpLogInfo struct {
+	Body        bool     `yaml:"body"`
+	MaxBodySize int      `yaml:"maxBodySize"`
+	Redact      []string `yaml:"redact"`
+}
+
+type HttpRetryInfo struct {
+	MaxRetries int `yaml:"maxRetries"`
+	BaseDelay  int `yaml:"baseDelay"`
+	MaxDelay   int `yaml:"maxDelay"`
+}
+
+type HttpBreakerInfo struct {
+	FailureThreshold int `yaml:"failureThreshold"`
+	OpenTimeout      int `yaml:"openTimeout"`
+	HalfOpenRequests int `yaml:"halfOpenRequests"`
+	SuccessThreshold int `yaml:"successThreshold"`
+}
+
+func (conf *AppConfInfo) validate() error {
+	if conf.Jwt != nil {
+		for i, info := range conf.Jwt.Keys {
+			if err := info.validate(); err != nil {
+				return fmt.Errorf("jwt.keys[%d]: %w", i, err)
+			}
+		}
+	}
+	for i, info := range conf.RateLimit {
+		if err := info.validate(); err != nil {
+			return fmt.Errorf("rateLimit[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
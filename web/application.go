@@ -2,6 +2,16 @@ package web
 
 import (
 	"catuan/comm"
+	"catuan/components/auth"
+	"catuan/components/i18n"
+	"catuan/components/metrics"
+	"catuan/components/rbac"
+	"catuan/components/reporters"
+	"catuan/components/sessions"
+	"catuan/components/storages"
+	"catuan/components/tracing"
+	"catuan/components/websockets"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -11,6 +21,7 @@ import (
 	"gorm.io/gorm"
 	"io"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -22,7 +33,7 @@ type Application struct {
 	runPath    string
 
 	roles  map[string]RoleInf
-	groups map[string]GroupInf
+	groups map[string]map[string]GroupInf // role -> group 路径 -> group
 
 	envPropertyHooks []AppPropertyHook
 	envProperties    map[string]string
@@ -30,6 +41,31 @@ type Application struct {
 	cdbChain    []*gorm.DB
 	credisChain []*redis.Client
 
+	jwt      *auth.JWT
+	sessions *sessions.Manager
+	rbac     rbac.Provider
+
+	rateLimits []*RateLimitRule
+
+	wsHub     *websockets.Hub
+	wsHubOnce sync.Once
+	storage   storages.Storage
+
+	i18n           *i18n.Bundle
+	i18nOnce       sync.Once
+	localeResolver LocaleResolver
+
+	reporters []reporters.Reporter
+
+	metrics         *actionMetrics
+	metricsRegistry *metrics.Registry
+	metricsOnce     sync.Once
+
+	tracer     *tracing.Tracer
+	tracerOnce sync.Once
+
+	syncDispatch bool
+
 	appConf *AppConfInfo
 }
 
@@ -51,7 +87,7 @@ func Default(args ...string) *Application {
 		version:    "1.0.0",
 		activeEnv:  activeEnv,
 		roles:      make(map[string]RoleInf),
-		groups:     make(map[string]GroupInf),
+		groups:     make(map[string]map[string]GroupInf),
 		configPath: configPath,
 
 		cdbChain:    make([]*gorm.DB, 0),
@@ -78,7 +114,7 @@ func New(args ...string) *Application {
 		version:    "1.0.0",
 		activeEnv:  activeEnv,
 		roles:      make(map[string]RoleInf),
-		groups:     make(map[string]GroupInf),
+		groups:     make(map[string]map[string]GroupInf),
 		configPath: configPath,
 
 		cdbChain:    make([]*gorm.DB, 0),
@@ -127,6 +163,10 @@ func (a *Application) loadConfigFile() {
 		}).Error(err.Error())
 		return
 	}
+	// 配置错误时启动失败, 避免限流等规则静默失效
+	if err = a.appConf.validate(); err != nil {
+		panic("配置错误: " + fileInfo + ": " + err.Error())
+	}
 }
 
 // EnvProperty 获取环境变量
@@ -167,6 +207,110 @@ func (a *Application) InitRedis() {
 	}
 }
 
+// validate 仅支持 HS256/RS256, 密钥不能为空
+func (info *JwtKeyInfo) validate() error {
+	switch info.Alg {
+	case auth.AlgHS256:
+		if info.Secret == "" {
+			return errors.New("empty secret: " + info.Kid)
+		}
+	case auth.AlgRS256:
+		if info.PrivateKeyFile == "" && info.PublicKeyFile == "" {
+			return errors.New("empty key file: " + info.Kid)
+		}
+	default:
+		return errors.New("unknown alg: " + info.Alg)
+	}
+	return nil
+}
+
+// InitJWT 根据配置加载 JWT 密钥
+func (a *Application) InitJWT() {
+	if a.appConf == nil || a.appConf.Jwt == nil {
+		return
+	}
+	conf := a.appConf.Jwt
+	j := auth.NewJWT(conf.Issuer, time.Duration(conf.Expires)*time.Second)
+	j.SetAudience(conf.Audience)
+	for _, info := range conf.Keys {
+		// 加载配置时已校验, 此处校验直接设置的配置
+		err := info.validate()
+		var key *auth.Key
+		if err == nil && info.Alg == auth.AlgRS256 {
+			key, err = auth.LoadRSKey(info.Kid, info.PrivateKeyFile, info.PublicKeyFile)
+		} else if err == nil {
+			key = auth.NewHSKey(info.Kid, info.Secret)
+		}
+		if err == nil {
+			err = j.AddKey(key, info.Kid == conf.ActiveKid)
+		}
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "加载JWT密钥失败",
+				"kid": info.Kid,
+			}).Error(err.Error())
+		}
+	}
+	a.jwt = j
+}
+
+func (a *Application) JWT() *auth.JWT {
+	return a.jwt
+}
+
+func (a *Application) SetJWT(j *auth.JWT) {
+	a.jwt = j
+}
+
+// InitSession 根据配置创建 redis 会话管理
+func (a *Application) InitSession() {
+	if a.appConf == nil || a.appConf.Session == nil {
+		return
+	}
+	conf := a.appConf.Session
+	rdb := a.GetRedis(conf.Redis)
+	if rdb == nil {
+		logrus.WithFields(logrus.Fields{
+			"tip":   "会话使用的redis不存在",
+			"redis": conf.Redis,
+		}).Error("session init failed")
+		return
+	}
+	a.sessions = sessions.NewManager(sessions.NewRedisStore(rdb, conf.Prefix), sessions.Options{
+		Header:     conf.Header,
+		Expires:    time.Duration(conf.Expires) * time.Second,
+		MaxPerUser: conf.MaxPerUser,
+	})
+}
+
+// UseSession 设置会话管理, 测试时可使用 sessions.NewMemoryStore
+func (a *Application) UseSession(m *sessions.Manager) {
+	a.sessions = m
+}
+
+func (a *Application) SessionManager() *sessions.Manager {
+	return a.sessions
+}
+
+// InitRBAC 未设置权限提供者时使用默认数据库中的权限表
+func (a *Application) InitRBAC() {
+	if a.rbac != nil {
+		return
+	}
+	if db := a.DBDefault(); db != nil {
+		a.rbac = rbac.NewGormProvider(db)
+	}
+}
+
+// RBAC 权限提供者, 由 SetRBAC 或 Init 设置
+func (a *Application) RBAC() rbac.Provider {
+	return a.rbac
+}
+
+func (a *Application) SetRBAC(p rbac.Provider) {
+	a.rbac = p
+}
+
 func (a *Application) SetCDB(cdb *gorm.DB, index int) {
 	if len(a.cdbChain) == 0 || index >= len(a.cdbChain) {
 		a.cdbChain = append(a.cdbChain, cdb)
@@ -179,6 +323,17 @@ func (a *Application) Init() {
 	a.runEnvPropertyHook()
 	a.InitDB()
 	a.InitRedis()
+	a.InitJWT()
+	a.InitSession()
+	a.InitRBAC()
+	a.InitRateLimit()
+	a.InitHttpClients()
+	a.InitWebSocket()
+	a.InitStorage()
+	a.InitI18n()
+	a.InitReporters()
+	a.InitMetrics()
+	a.InitTracing()
 }
 
 func (a *Application) runEnvPropertyHook() {
@@ -214,26 +369,53 @@ func (a *Application) UseRole(roles ...RoleInf) {
 	for _, role := range roles {
 		_, ok := a.roles[role.RoleLabel()]
 		if ok {
-			panic("role already exists")
+			panic("role already exists: " + role.RoleLabel())
 		}
 		a.roles[role.RoleLabel()] = role
 	}
 }
 
 func (a *Application) FindGroup(roleLabel, groupLabel string) (GroupInf, bool) {
-	key := roleLabel + "-" + groupLabel
-	groupInf, ok := a.groups[key]
+	groupInf, ok := a.groups[roleLabel][groupLabel]
 	return groupInf, ok
 }
 
+// UseGroup 将分组注册到分组所属的角色下
 func (a *Application) UseGroup(groups ...GroupInf) {
 	for _, group := range groups {
-		key := group.RoleLabel() + "-" + group.GroupLabel()
-		_, ok := a.groups[key]
-		if ok {
-			panic("group already exists")
+		a.MountGroup(group, group.RoleLabel())
+	}
+}
+
+// MountGroup 将同一个分组挂载到多个角色下, 子分组一并挂载, 子分组需在挂载前创建
+// 路径重复时 panic, 此时不会挂载任何分组
+func (a *Application) MountGroup(group GroupInf, roleLabels ...string) {
+	type mount struct {
+		roleLabel string
+		group     GroupInf
+	}
+	mounts := make([]mount, 0)
+	seen := make(map[string]bool)
+	var collect func(roleLabel string, g GroupInf)
+	collect = func(roleLabel string, g GroupInf) {
+		path := roleLabel + "/" + g.GroupLabel()
+		if _, ok := a.groups[roleLabel][g.GroupLabel()]; ok || seen[path] {
+			panic("group already exists: " + path)
 		}
-		a.groups[group.GroupLabel()] = group
+		seen[path] = true
+		mounts = append(mounts, mount{roleLabel: roleLabel, group: g})
+		for _, sub := range g.SubGroups() {
+			collect(roleLabel, sub)
+		}
+	}
+	for _, roleLabel := range roleLabels {
+		collect(roleLabel, group)
+	}
+	for _, m := range mounts {
+		if _, ok := a.groups[m.roleLabel]; !ok {
+			a.groups[m.roleLabel] = make(map[string]GroupInf)
+		}
+		a.groups[m.roleLabel][m.group.GroupLabel()] = m.group
 	}
 }
 
@@ -244,6 +426,7 @@ func (a *Application) HttpServerRun() error {
 			defaultPort = a.appConf.Web.Http.Port
 		}
 	}
+	a.warnUnguardedPermissions()
 	err := a.Run(":" + defaultPort)
 	return err
 }
@@ -263,31 +446,58 @@ func (a *Application) HttpsServerRun() error {
 			defaultKeyFile = a.appConf.Web.Https.KeyFile
 		}
 	}
+	a.warnUnguardedPermissions()
 	err := a.RunTLS(":"+defaultPort, defaultCertFile, defaultKeyFile)
 	return err
 }
 
+// SyncDispatch 开启后 Router 在当前协程处理请求, 不启动协程也不使用默认超时, 用于测试
+func (a *Application) SyncDispatch(enabled bool) {
+	a.syncDispatch = enabled
+}
+
 func (a *Application) Router(c *Context) {
+	c.app = a
+	c.Header(RequestIDHeader, c.RequestID())
+	a.startTrace(c)
+	start := time.Now()
 	defaultTimeout := time.Second * 5
+	meta, ok := c.ActionMeta()
+	if a.syncDispatch || ok && (meta.Stream || meta.WebSocket || meta.Download) {
+		a.routeSync(c, meta)
+		a.finishRequest(c, start, c.resp, false)
+		return
+	}
+	if ok && meta.Timeout > 0 {
+		defaultTimeout = meta.Timeout
+	}
 	go func() {
 		defer func() {
-			// recover from panic
-			if err := recover(); err != nil {
-				logrus.WithFields(logrus.Fields{
-					"role":   c.RoleLabel(),
-					"group":  c.GroupLabel(),
-					"action": c.ActionLabel(),
-				}).Error(err)
+			// panic 时立即返回通用错误, 不等待超时
+			if r := recover(); r != nil {
+				resp := a.handlePanic(c, r)
+				// 与同步处理一致, panic 时不保存会话
+				c.session = nil
+				select {
+				case c.respChan <- resp:
+				default:
+				}
 			}
 		}()
 		a.router(c)
+		c.flush()
 	}()
 	select {
 	case <-time.After(defaultTimeout):
-		c.JsonResponse(&comm.RespResult{ErrCode: -1, ErrMsg: "timeout"})
+		resp := comm.ErrTimeout.Result()
+		c.WriteResponse(resp)
+		a.finishRequest(c, start, resp, true)
 		return
 	case resp := <-c.RespChannel():
-		c.JsonResponse(resp)
+		// 会话在当前协程保存, 超时后处理协程不再写响应头
+		c.saveSession()
+		c.WriteResponse(resp)
+		a.finishRequest(c, start, resp, false)
 	case <-c.Done():
 		return
 	}
@@ -296,26 +506,31 @@ func (a *Application) Router(c *Context) {
 func (a *Application) router(c *Context) {
 	role, ok := a.FindRole(c.RoleLabel())
 	if !ok {
-		c.Result(-1, "access denied,role not found")
+		c.Fail(comm.ErrForbidden.WithKey("error.role_not_found", "access denied,role not found"))
 		return
 	}
 	group, ok := a.FindGroup(c.RoleLabel(), c.GroupLabel())
 	if !ok {
-		c.Result(-1, "access denied,group not found")
+		c.Fail(comm.ErrForbidden.WithKey("error.group_not_found", "access denied,group not found"))
 		return
 	}
-	if !ok {
-		c.Result(-1, "access denied,action not found")
-		return
-	}
-	role.Call(c)
-	if !c.IsNext() {
-		return
-	}
-	group.Call(c)
-	if !c.IsNext() {
+	// 流式/websocket/下载自行决定响应格式, 其余 action 在执行前检查 Accept
+	if meta, ok := c.ActionMeta(); (!ok || !(meta.Stream || meta.WebSocket || meta.Download)) && c.Encoding() == "" {
+		c.Fail(comm.ErrNotAcceptable)
 		return
 	}
+	role.Invoke(c, func() {
+		allowed := true
+		if len(a.rateLimits) > 0 {
+			c.tracePhase("ratelimit", func() {
+				allowed = a.checkRateLimit(c)
+			})
+		}
+		if !allowed {
+			return
+		}
+		group.Call(c)
+	})
 }
 
 func (a *Application) DBDefault() *gorm.DB {
@@ -326,7 +541,7 @@ func (a *Application) DBDefault() *gorm.DB {
 }
 
 func (a *Application) GetDB(i int) *gorm.DB {
-	if a.cdbChain == nil || len(a.cdbChain)-1 <= i {
+	if a.cdbChain == nil || i < 0 || i >= len(a.cdbChain) {
 		return nil
 	}
 	return a.cdbChain[i]
@@ -340,7 +555,7 @@ func (a *Application) RedisDefault() *redis.Client {
 }
 
 func (a *Application) GetRedis(i int) *redis.Client {
-	if a.credisChain == nil || len(a.credisChain)-1 <= i {
+	if a.credisChain == nil || i < 0 || i >= len(a.credisChain) {
 		return nil
 	}
 	return a.credisChain[i]
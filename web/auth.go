@@ -0,0 +1,98 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/auth"
+	"errors"
+	"strings"
+)
+
+// AuthOrder 鉴权 handler 的执行顺序, 先于普通 before handler 执行
+const AuthOrder = -1000
+
+// Claims 当前请求的 token 载荷, 未通过鉴权时为 nil
+func (c *Context) Claims() *auth.Claims {
+	return c.claims
+}
+
+func (c *Context) SetClaims(claims *auth.Claims) {
+	c.claims = claims
+}
+
+// UserID 当前登录用户标识, 优先取 token, 其次取会话
+func (c *Context) UserID() string {
+	if c.claims != nil {
+		return c.claims.Subject
+	}
+	if c.session != nil {
+		return c.session.UserID()
+	}
+	return ""
+}
+
+// BearerToken 读取 Authorization: Bearer xxx, 兼容 Token 请求头, websocket 握手时兼容 access_token 参数
+func (c *Context) BearerToken() string {
+	authorization := c.GetHeader("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "Bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	if token := c.GetHeader("Token"); token != "" {
+		return token
+	}
+	// 浏览器建立 websocket 连接时无法设置请求头, 从 url 参数中获取
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		return c.Query("access_token")
+	}
+	return ""
+}
+
+// JWTAuth 验证 token 并将载荷写入 Context, j 为 nil 时在请求时使用 Application 的 JWT, 均未配置时拒绝请求
+func JWTAuth(j *auth.JWT) HandlerFunc {
+	return func(c *Context) {
+		verifier := j
+		if verifier == nil && c.app != nil {
+			verifier = c.app.JWT()
+		}
+		if verifier == nil {
+			c.Fail(comm.ErrFail.Wrap(errors.New("JWT 未配置")))
+			return
+		}
+		token := c.BearerToken()
+		if token == "" {
+			c.Fail(comm.ErrUnauthorized)
+			return
+		}
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			if errors.Is(err, auth.ErrTokenExpired) {
+				c.Fail(comm.ErrUnauthorized.WithKey("error.token_expired", err.Error()))
+				return
+			}
+			c.Fail(comm.ErrUnauthorized.WithKey("error.token_invalid", err.Error()))
+			return
+		}
+		c.SetClaims(claims)
+	}
+}
+
+// MatchTokenRole 要求 token 中的角色与请求的角色一致, 需在 JWTAuth 之后执行
+func MatchTokenRole() HandlerFunc {
+	return func(c *Context) {
+		if c.claims == nil {
+			c.Fail(comm.ErrUnauthorized)
+			return
+		}
+		if c.claims.Role != c.RoleLabel() {
+			c.Fail(comm.ErrForbidden.WithKey("error.role_mismatch", "access denied,role mismatch"))
+		}
+	}
+}
+
+// RequireAuth 角色下所有 action 需要登录, matchRole 为 true 时 token 角色须与当前角色一致
+// j 为 nil 时使用 Application 的 JWT, 可在 Init 之前注册
+func (r *Role) RequireAuth(j *auth.JWT, matchRole bool) {
+	r.UseBefore(AuthOrder, JWTAuth(j))
+	if matchRole {
+		r.UseBefore(AuthOrder, MatchTokenRole())
+	}
+}
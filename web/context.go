@@ -2,7 +2,14 @@ package web
 
 import (
 	"catuan/comm"
+	"catuan/components/auth"
+	"catuan/components/sessions"
+	"catuan/components/tracing"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
 )
 
 type Context struct {
@@ -10,9 +17,24 @@ type Context struct {
 	isNext bool
 
 	respChan    chan *comm.RespResult
+	resp        *comm.RespResult
 	actionLabel string
 	roleLabel   string
 	groupLabel  string
+
+	claims  *auth.Claims
+	app     *Application
+	session *sessions.Session
+	written bool // 流式/websocket/下载 action 已直接写入响应
+
+	locale     string
+	localeOnce sync.Once
+	requestID  string
+
+	handlerObserver func(stage, name string)
+
+	traceSpan *tracing.Span // 请求 span
+	span      *tracing.Span // 当前阶段 span, 只在处理请求的协程中读写
 }
 
 func NewContext(c *gin.Context) *Context {
@@ -29,31 +51,97 @@ func (c *Context) InitRoleInfo(roleLabel, groupLabel, actionLabel string) {
 	c.actionLabel = actionLabel
 }
 
+// Result 设置响应结果, 多次调用以最后一次为准, 所有 handler 执行完成后发送
 func (c *Context) Result(errCode int, errMsg string, data ...interface{}) {
 	if len(data) == 0 {
-		c.respChan <- &comm.RespResult{
+		c.resp = &comm.RespResult{
 			ErrCode: errCode,
 			ErrMsg:  errMsg,
 		}
 		return
 	}
 	if len(data) == 1 {
-		c.respChan <- &comm.RespResult{
+		c.resp = &comm.RespResult{
 			ErrCode: errCode,
 			ErrMsg:  errMsg,
 			Data:    data[0],
 		}
 		return
 	}
-	c.respChan <- &comm.RespResult{
+	c.resp = &comm.RespResult{
 		ErrCode: errCode,
 		ErrMsg:  errMsg,
 		Data:    data,
 	}
 }
 
+// Fail 根据错误设置响应结果并中断后续 handler
+// 错误链中包含 comm.Error 时使用其 code 与消息, 否则返回通用错误, 详细信息记录到日志
+func (c *Context) Fail(err error) {
+	c.resp = c.errorResult(err)
+	c.AbortHandler()
+}
+
+func (c *Context) errorResult(err error) *comm.RespResult {
+	fields := logrus.Fields{
+		"requestId": c.RequestID(),
+		"role":      c.RoleLabel(),
+		"group":     c.GroupLabel(),
+		"action":    c.ActionLabel(),
+	}
+	if traceID := c.TraceID(); traceID != "" {
+		fields["traceId"] = traceID
+	}
+	var e *comm.Error
+	if errors.As(err, &e) {
+		if e.Status >= http.StatusInternalServerError {
+			c.Span().SetError(err)
+		}
+		if e.Cause() != nil {
+			fields["tip"] = e.Message
+			// 客户端错误的原因仅作为普通日志记录
+			if e.Status >= http.StatusInternalServerError {
+				logrus.WithFields(fields).Warn(e.Cause().Error())
+			} else {
+				logrus.WithFields(fields).Info(e.Cause().Error())
+			}
+		}
+		return e.Result()
+	}
+	fields["tip"] = "未知错误"
+	c.Span().SetError(err)
+	logrus.WithFields(fields).Error(err.Error())
+	return comm.ErrFail.Result()
+}
+
+// Response 当前的响应结果, 未设置时为 nil
+func (c *Context) Response() *comm.RespResult {
+	return c.resp
+}
+
+// SetResponse 替换响应结果, 一般在 after/around handler 中使用
+func (c *Context) SetResponse(resp *comm.RespResult) {
+	c.resp = resp
+}
+
+// flush 发送响应结果
+func (c *Context) flush() {
+	if c.resp != nil {
+		c.respChan <- c.resp
+	}
+}
+
+// JsonResponse 以 json 写入响应, 消息按请求语言翻译
 func (c *Context) JsonResponse(resp *comm.RespResult) {
-	c.JSON(200, resp)
+	resp = c.localize(resp)
+	c.JSON(respStatus(resp), resp)
+}
+
+func respStatus(resp *comm.RespResult) int {
+	if resp.Status == 0 {
+		return http.StatusOK
+	}
+	return resp.Status
 }
 
 func (c *Context) IsNext() bool {
@@ -0,0 +1,377 @@
+package web
+
+import (
+	"bytes"
+	"catuan/comm"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	EncodingJSON     = "json"
+	EncodingXML      = "xml"
+	EncodingMsgPack  = "msgpack"
+	EncodingProtobuf = "protobuf"
+)
+
+// Encoder 响应编码器, 将 RespResult 编码后写入 w
+type Encoder interface {
+	ContentType() string
+	Encode(w io.Writer, resp *comm.RespResult) error
+}
+
+type encoderEntry struct {
+	name       string
+	encoder    Encoder
+	mediaTypes []string
+}
+
+var (
+	encoderMu sync.RWMutex
+	encoders  = make(map[string]*encoderEntry)
+	// encoderOrder 注册顺序, 优先级相同时靠前的优先
+	encoderOrder = make([]string, 0)
+)
+
+func init() {
+	RegisterEncoder(EncodingJSON, jsonEncoder{}, "application/json", "text/json")
+	RegisterEncoder(EncodingXML, xmlEncoder{}, "application/xml", "text/xml")
+	RegisterEncoder(EncodingMsgPack, msgpackEncoder{}, "application/msgpack", "application/x-msgpack")
+	RegisterEncoder(EncodingProtobuf, protobufEncoder{}, "application/x-protobuf", "application/protobuf")
+}
+
+// RegisterEncoder 注册编码器, mediaTypes 为 Accept 中匹配的媒体类型, 同名时覆盖
+func RegisterEncoder(name string, enc Encoder, mediaTypeList ...string) {
+	encoderMu.Lock()
+	defer encoderMu.Unlock()
+	entry := &encoderEntry{name: name, encoder: enc}
+	for _, mediaType := range mediaTypeList {
+		mediaType = strings.ToLower(mediaType)
+		entry.mediaTypes = append(entry.mediaTypes, mediaType)
+	}
+	if _, ok := encoders[name]; !ok {
+		encoderOrder = append(encoderOrder, name)
+	}
+	encoders[name] = entry
+}
+
+// FindEncoder 按名称查找编码器
+func FindEncoder(name string) (Encoder, bool) {
+	encoderMu.RLock()
+	defer encoderMu.RUnlock()
+	entry, ok := encoders[name]
+	if !ok {
+		return nil, false
+	}
+	return entry.encoder, true
+}
+
+type acceptItem struct {
+	mediaType string
+	q         float64
+}
+
+// NegotiateEncoding 根据 Accept 选择编码, q 值高的优先, q 值相同时具体的媒体类型优先于通配
+// 编码器按实际返回的 Content-Type 匹配通配与 q=0 排除, 注册的其他媒体类型需在 Accept 中完全匹配
+// Accept 为空时使用 json, 没有可接受的编码时返回空字符串
+func NegotiateEncoding(accept string) string {
+	items := parseAccept(accept)
+	if len(items) == 0 {
+		return EncodingJSON
+	}
+	encoderMu.RLock()
+	defer encoderMu.RUnlock()
+	best := ""
+	bestQ, bestSpec, bestIndex := 0.0, -1, 0
+	choose := func(name string, q float64, spec int, index int) {
+		if q <= 0 {
+			return
+		}
+		if q > bestQ || q == bestQ && (spec > bestSpec || spec == bestSpec && index < bestIndex) {
+			best = name
+			bestQ, bestSpec, bestIndex = q, spec, index
+		}
+	}
+	for _, name := range encoderOrder {
+		entry := encoders[name]
+		contentType, _, _ := strings.Cut(entry.encoder.ContentType(), ";")
+		contentType = strings.ToLower(strings.TrimSpace(contentType))
+		q, spec, index := acceptQuality(items, contentType)
+		choose(name, q, spec, index)
+		if spec == 2 {
+			continue
+		}
+		for _, mediaType := range entry.mediaTypes {
+			if q, spec, index = acceptQuality(items, mediaType); spec == 2 && mediaType != contentType {
+				choose(name, q, spec, index)
+			}
+		}
+	}
+	return best
+}
+
+func parseAccept(accept string) []acceptItem {
+	items := make([]acceptItem, 0)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		item := acceptItem{mediaType: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		if item.mediaType == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					item.q = q
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// acceptQuality 使用最具体的匹配项的 q 值, spec 为 2 完全匹配, 1 为 type/*, 0 为 */*, index 为匹配项在 Accept 中的位置
+func acceptQuality(items []acceptItem, mediaType string) (q float64, spec int, index int) {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	spec = -1
+	for i, item := range items {
+		itemSpec := -1
+		switch item.mediaType {
+		case mediaType:
+			itemSpec = 2
+		case mainType + "/*":
+			itemSpec = 1
+		case "*/*":
+			itemSpec = 0
+		}
+		if itemSpec > spec {
+			q, spec, index = item.q, itemSpec, i
+		}
+	}
+	if spec < 0 {
+		return 0, spec, 0
+	}
+	return q, spec, index
+}
+
+// Encoding 当前请求使用的响应编码, action 通过 WithEncoding 指定时优先使用, 没有可接受的编码时为空
+func (c *Context) Encoding() string {
+	if meta, ok := c.ActionMeta(); ok && meta.Encoding != "" {
+		return meta.Encoding
+	}
+	return NegotiateEncoding(c.GetHeader("Accept"))
+}
+
+// WriteResponse 按协商的编码写入响应, 编码失败时使用 json 返回错误信息
+func (c *Context) WriteResponse(resp *comm.RespResult) {
+	resp = c.localize(resp)
+	name := c.Encoding()
+	enc, ok := FindEncoder(name)
+	if !ok {
+		enc = jsonEncoder{}
+	}
+	buf := &bytes.Buffer{}
+	if err := enc.Encode(buf, resp); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"tip":      "响应编码失败",
+			"encoding": name,
+		}).Error(err.Error())
+		c.JsonResponse(comm.ErrFail.Result())
+		return
+	}
+	c.Header("Vary", "Accept")
+	c.Data(respStatus(resp), enc.ContentType(), buf.Bytes())
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (jsonEncoder) Encode(w io.Writer, resp *comm.RespResult) error {
+	return json.NewEncoder(w).Encode(resp)
+}
+
+type xmlEncoder struct{}
+
+// xmlResult xml 响应结构, 根节点为 result
+type xmlResult struct {
+	XMLName xml.Name `xml:"result"`
+	ErrCode int      `xml:"err_code"`
+	ErrMsg  string   `xml:"message"`
+	Data    any      `xml:"data,omitempty"`
+}
+
+func (xmlEncoder) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (xmlEncoder) Encode(w io.Writer, resp *comm.RespResult) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	data, err := jsonValue(resp.Data)
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(xmlResult{
+		ErrCode: resp.ErrCode,
+		ErrMsg:  resp.ErrMsg,
+		Data:    xmlValue(data),
+	})
+}
+
+// jsonValue 经 json 转换, 保持与 json 响应相同的字段名
+func jsonValue(data any) (any, error) {
+	if data == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var v any
+	if err = decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// xmlMap encoding/xml 不支持 map, 按 key 排序后逐个编码为子节点
+type xmlMap map[string]any
+
+func (m xmlMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := e.EncodeElement(m[key], xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// xmlValue 将 map 转换为 xmlMap, 切片中的 map 同样转换
+func xmlValue(v any) any {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		m := make(xmlMap, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = xmlValue(iter.Value().Interface())
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		items := make([]any, rv.Len())
+		for i := range items {
+			items[i] = xmlValue(rv.Index(i).Interface())
+		}
+		return items
+	}
+	return v
+}
+
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+// Encode 字段名与 json tag 一致
+func (msgpackEncoder) Encode(w io.Writer, resp *comm.RespResult) error {
+	handle := &codec.MsgpackHandle{}
+	handle.WriteExt = true
+	return codec.NewEncoder(w, handle).Encode(resp)
+}
+
+type protobufEncoder struct{}
+
+func (protobufEncoder) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// Encode 编码为以下结构, data 为 proto.Message 时直接打包, 否则转换为 google.protobuf.Value
+//
+//	message RespResult {
+//	  int32 err_code = 1;
+//	  string message = 2;
+//	  google.protobuf.Any data = 3;
+//	}
+func (protobufEncoder) Encode(w io.Writer, resp *comm.RespResult) error {
+	var b []byte
+	if resp.ErrCode != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(int64(resp.ErrCode)))
+	}
+	if resp.ErrMsg != "" {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, resp.ErrMsg)
+	}
+	if resp.Data != nil {
+		data, err := protoAny(resp.Data)
+		if err != nil {
+			return err
+		}
+		raw, err := proto.Marshal(data)
+		if err != nil {
+			return err
+		}
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, raw)
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func protoAny(data any) (*anypb.Any, error) {
+	if msg, ok := data.(proto.Message); ok {
+		return anypb.New(msg)
+	}
+	// 非 proto 类型经 json 转换, 保持与 json 响应相同的字段名
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err = json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	value, err := structpb.NewValue(v)
+	if err != nil {
+		return nil, errors.New("data 无法转换为 protobuf: " + err.Error())
+	}
+	return anypb.New(value)
+}
@@ -0,0 +1,367 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/storages"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// UploadOption 上传限制
+type UploadOption struct {
+	Field      string   // 表单字段名, 默认 file
+	MaxSize    int64    // 单个文件大小上限 字节, 默认 10MB
+	MaxFiles   int      // 文件数量上限, 默认 1
+	AllowTypes []string // 允许的类型, 支持 image/png, image/*, .pdf 格式, 为空不限制
+	Prefix     string   // 保存路径前缀
+	Storage    storages.Storage
+	// KeyFunc 生成保存路径, 默认 前缀/年/月/日/随机名.扩展名
+	KeyFunc func(c *Context, header *multipart.FileHeader) string
+}
+
+// UploadedFile 已保存的文件
+type UploadedFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// UploadHandlerFunc 文件保存后调用, 为空时以文件列表作为 data 响应
+type UploadHandlerFunc func(c *Context, files []*UploadedFile)
+
+// BindUpload 绑定上传 action, 解析 multipart 表单, 校验大小与类型后保存到存储, 默认超时 1 分钟
+func (g *Group) BindUpload(action string, opt UploadOption, h UploadHandlerFunc, opts ...ActionOption) {
+	if opt.Field == "" {
+		opt.Field = "file"
+	}
+	if opt.MaxSize <= 0 {
+		opt.MaxSize = 10 << 20
+	}
+	if opt.MaxFiles <= 0 {
+		opt.MaxFiles = 1
+	}
+	opts = append([]ActionOption{WithTimeout(time.Minute)}, opts...)
+	opts = append(opts, func(meta *ActionMeta) {
+		meta.Upload = true
+	})
+	g.BindAction(action, func(c *Context) {
+		files, err := c.saveUploads(opt)
+		if err != nil {
+			c.Fail(err)
+			return
+		}
+		if h == nil {
+			c.Result(comm.ErrCodeSuccess, "success", files)
+			return
+		}
+		h(c, files)
+	}, opts...)
+}
+
+func (c *Context) saveUploads(opt UploadOption) ([]*UploadedFile, error) {
+	storage := opt.Storage
+	if storage == nil {
+		storage = c.app.Storage()
+	}
+	if storage == nil {
+		return nil, comm.ErrFail.Wrap(errors.New("未配置文件存储"))
+	}
+	// 表单其他字段预留 1MB
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, opt.MaxSize*int64(opt.MaxFiles)+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, comm.ErrBadRequest.Wrap(err).WithKey("upload.invalid_form", "文件过大或表单格式错误")
+	}
+	headers := form.File[opt.Field]
+	if len(headers) == 0 {
+		return nil, comm.ErrBadRequest.WithKey("upload.no_file", "请选择文件")
+	}
+	if len(headers) > opt.MaxFiles {
+		return nil, comm.ErrBadRequest.WithKey("upload.too_many_files", "文件数量超出限制")
+	}
+	files := make([]*UploadedFile, 0, len(headers))
+	for _, header := range headers {
+		if header.Size > opt.MaxSize {
+			return nil, comm.ErrBadRequest.
+				WithKey("upload.too_large", "文件大小超出限制: {filename}").
+				WithArgs(map[string]any{"filename": header.Filename})
+		}
+		file, err := c.saveUpload(storage, opt, header)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (c *Context) saveUpload(storage storages.Storage, opt UploadOption, header *multipart.FileHeader) (*UploadedFile, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// 根据文件内容判断类型, 无法识别时根据扩展名判断
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	ext := strings.ToLower(path.Ext(header.Filename))
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	// 扩展名不能将内容升级为 html/svg/xml 等浏览器可执行脚本的类型
+	if contentType == "application/octet-stream" || contentType == "text/plain" || contentType == "application/zip" && zipExtensions[ext] {
+		if byExt, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil && !activeType(byExt) {
+			contentType = byExt
+		}
+	}
+	// 存储按扩展名返回类型, 扩展名与内容不一致时按内容类型重新生成
+	ext = extensionFor(contentType, ext)
+	if !allowType(opt.AllowTypes, contentType, ext) {
+		return nil, comm.ErrBadRequest.
+			WithKey("upload.type_not_allowed", "不支持的文件类型: {filename}").
+			WithArgs(map[string]any{"filename": header.Filename})
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var key string
+	if opt.KeyFunc != nil {
+		key = opt.KeyFunc(c, header)
+	} else {
+		key = path.Join(opt.Prefix, comm.Now().Format("2006/01/02"), randomName()+ext)
+	}
+	obj, err := storage.Put(c.Request.Context(), key, f, header.Size, contentType)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"tip": "保存上传文件失败",
+			"key": key,
+		}).Error(err.Error())
+		return nil, comm.ErrFail.WithKey("upload.save_failed", "保存文件失败")
+	}
+	return &UploadedFile{
+		Field:       opt.Field,
+		Filename:    header.Filename,
+		Key:         obj.Key,
+		Size:        obj.Size,
+		ContentType: contentType,
+	}, nil
+}
+
+// zipExtensions 内容为 zip 的文档格式, 按扩展名确定类型
+var zipExtensions = map[string]bool{
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".ods": true, ".odp": true, ".epub": true,
+}
+
+// preferredExtensions 同一类型有多个扩展名时使用的扩展名
+var preferredExtensions = map[string]string{
+	"image/jpeg":               ".jpg",
+	"text/plain":               ".txt",
+	"text/html":                ".html",
+	"text/xml":                 ".xml",
+	"application/octet-stream": ".bin",
+}
+
+// extensionFor 扩展名对应的类型与内容类型一致时保留, 否则使用内容类型的扩展名, 没有对应扩展名时为空
+func extensionFor(contentType, ext string) string {
+	if byExt, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil && byExt == contentType {
+		return ext
+	}
+	if preferred, ok := preferredExtensions[contentType]; ok {
+		return preferred
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// activeType 浏览器直接打开时可执行脚本的类型
+func activeType(contentType string) bool {
+	switch contentType {
+	case "text/html", "text/xml", "application/xml", "application/xhtml+xml", "image/svg+xml":
+		return true
+	}
+	return strings.HasSuffix(contentType, "+xml")
+}
+
+func allowType(allowTypes []string, contentType, ext string) bool {
+	if len(allowTypes) == 0 {
+		return true
+	}
+	for _, allow := range allowTypes {
+		allow = strings.ToLower(allow)
+		switch {
+		case strings.HasPrefix(allow, "."):
+			if allow == ext {
+				return true
+			}
+		case strings.HasSuffix(allow, "/*"):
+			if strings.HasPrefix(contentType, strings.TrimSuffix(allow, "*")) {
+				return true
+			}
+		case allow == contentType:
+			return true
+		}
+	}
+	return false
+}
+
+func randomName() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Download 下载的文件
+type Download struct {
+	Key      string
+	Filename string           // 保存的文件名, 为空时使用 key 的文件名
+	Inline   bool             // 在浏览器中直接打开, html/svg/xml 类型附加 CSP sandbox
+	Storage  storages.Storage // 为空时使用默认存储
+	// Redirect 大于 0 时跳转到有效期为 Redirect 的签名地址, 不经过应用转发
+	Redirect time.Duration
+}
+
+// DownloadHandlerFunc 返回要下载的文件, 返回 error 时按普通响应返回
+type DownloadHandlerFunc func(c *Context) (*Download, error)
+
+// BindDownload 绑定下载 action, 支持 Range 分段下载
+func (g *Group) BindDownload(action string, h DownloadHandlerFunc, opts ...ActionOption) {
+	opts = append(opts, func(meta *ActionMeta) {
+		meta.Download = true
+	})
+	g.BindAction(action, func(c *Context) {
+		d, err := h(c)
+		if err != nil {
+			c.Fail(err)
+			return
+		}
+		if err = c.serveDownload(d); err != nil {
+			c.Fail(err)
+		}
+	}, opts...)
+}
+
+func (c *Context) serveDownload(d *Download) error {
+	storage := d.Storage
+	if storage == nil {
+		storage = c.app.Storage()
+	}
+	if storage == nil {
+		return comm.ErrFail.Wrap(errors.New("未配置文件存储"))
+	}
+	if d.Redirect > 0 {
+		signed, err := storage.SignURL(c.Request.Context(), d.Key, d.Redirect)
+		if err != nil {
+			return err
+		}
+		c.written = true
+		c.saveSession()
+		c.Redirect(http.StatusFound, signed)
+		return nil
+	}
+	f, obj, err := storage.Open(c.Request.Context(), d.Key)
+	if errors.Is(err, storages.ErrNotFound) {
+		return comm.ErrNotFound.WithKey("file.not_found", err.Error())
+	}
+	if errors.Is(err, storages.ErrInvalidKey) {
+		return comm.ErrBadRequest.WithKey("file.invalid_key", err.Error())
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	filename := d.Filename
+	if filename == "" {
+		filename = path.Base(obj.Key)
+	}
+	disposition := "attachment"
+	if d.Inline {
+		disposition = "inline"
+	}
+	c.written = true
+	c.saveSession()
+	header := c.Writer.Header()
+	header.Set("Content-Type", obj.ContentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if d.Inline {
+		if contentType, _, err := mime.ParseMediaType(obj.ContentType); err != nil || activeType(contentType) {
+			// 禁止脚本执行, 避免上传的 html/svg 在当前域下运行
+			header.Set("Content-Security-Policy", "sandbox")
+		}
+	}
+	header.Set("Content-Disposition", disposition+"; filename*=UTF-8''"+url.PathEscape(filename))
+	if obj.ETag != "" {
+		header.Set("ETag", obj.ETag)
+	}
+	http.ServeContent(c.Writer, c.Request, filename, obj.ModTime, f)
+	return nil
+}
+
+// InitStorage 根据配置创建默认文件存储, 本地存储配置 servePath 时注册签名下载路由
+func (a *Application) InitStorage() {
+	if a.appConf == nil || a.appConf.Storage == nil {
+		return
+	}
+	info := a.appConf.Storage
+	switch info.Type {
+	case "s3", "cos":
+		storage, err := storages.NewS3Storage(storages.S3Config{
+			Endpoint:  info.Endpoint,
+			Region:    info.Region,
+			Bucket:    info.Bucket,
+			AccessKey: info.AccessKey,
+			SecretKey: info.SecretKey,
+			PathStyle: info.PathStyle,
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "初始化文件存储失败",
+			}).Error(err.Error())
+			return
+		}
+		a.storage = storage
+	default:
+		storage, err := storages.NewLocalStorage(info.Root, info.BaseURL, info.Secret)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "初始化文件存储失败",
+			}).Error(err.Error())
+			return
+		}
+		a.storage = storage
+		if info.ServePath != "" {
+			a.ServeLocalStorage(info.ServePath, storage)
+		}
+	}
+}
+
+// ServeLocalStorage 注册本地存储签名地址的下载路由
+func (a *Application) ServeLocalStorage(servePath string, storage *storages.LocalStorage) {
+	servePath = strings.TrimSuffix(servePath, "/")
+	handler := http.StripPrefix(servePath, storage.Handler())
+	a.GET(servePath+"/*key", gin.WrapH(handler))
+	a.HEAD(servePath+"/*key", gin.WrapH(handler))
+}
+
+func (a *Application) Storage() storages.Storage {
+	return a.storage
+}
+
+func (a *Application) SetStorage(storage storages.Storage) {
+	a.storage = storage
+}
@@ -1,30 +1,60 @@
 package web
 
+import (
+	"catuan/comm"
+	"sort"
+)
+
 type GroupInf interface {
 	GroupLabel() string
 	RoleLabel() string
 	Call(c *Context)
 
 	UseBefore(h HandlerFunc, destAction ...string)
+	UseBeforeWithOrder(orderNum int, h HandlerFunc, destAction ...string)
+	UseAfter(h HandlerFunc, destAction ...string)
+	UseAround(h AroundFunc, destAction ...string)
 	FindAction(actionName string) (HandlerFunc, bool)
-	BindAction(actionName string, handler HandlerFunc)
+	BindAction(actionName string, handler HandlerFunc, opts ...ActionOption)
+	BindStream(actionName string, handler StreamHandlerFunc, opts ...ActionOption)
+	BindWebSocket(actionName string, handler WebSocketHandlerFunc, opts ...ActionOption)
+	FindActionMeta(actionName string) (*ActionMeta, bool)
+	ActionNames() []string
+	BeforeHandlers(actionName string) []HandlerFunc
+	BeforeChain(actionName string) []OrderedHandler
+	SubGroups() []GroupInf
 }
 
+var _ GroupInf = (*Group)(nil)
+
 type Group struct {
 	groupName      string
 	roleName       string
-	beforeHandlers map[string][]HandlerFunc
+	parent         *Group
+	children       []*Group
+	beforeHandlers map[string][]OrderedHandler
 	actionHandlers map[string]HandlerFunc
-	commHandlers   []HandlerFunc
+	actionMetas    map[string]*ActionMeta
+	commHandlers   []OrderedHandler
+
+	afterHandlers  map[string][]HandlerFunc
+	aroundHandlers map[string][]AroundFunc
+	commAfters     []HandlerFunc
+	commArounds    []AroundFunc
 }
 
 func NewGroup(roleLabel string, groupLabel string) *Group {
 	return &Group{
 		groupName:      groupLabel,
 		roleName:       roleLabel,
-		beforeHandlers: make(map[string][]HandlerFunc),
+		beforeHandlers: make(map[string][]OrderedHandler),
 		actionHandlers: make(map[string]HandlerFunc),
-		commHandlers:   make([]HandlerFunc, 0),
+		actionMetas:    make(map[string]*ActionMeta),
+		commHandlers:   make([]OrderedHandler, 0),
+		afterHandlers:  make(map[string][]HandlerFunc),
+		aroundHandlers: make(map[string][]AroundFunc),
+		commAfters:     make([]HandlerFunc, 0),
+		commArounds:    make([]AroundFunc, 0),
 	}
 }
 
@@ -36,57 +66,174 @@ func (g *Group) RoleLabel() string {
 	return g.roleName
 }
 
+// Call 依次执行 before handler, around handler 包裹的 action handler, after handler
 func (g *Group) Call(c *Context) {
-	//执行Before handler
-	if g.commHandlers != nil {
-		for _, h := range g.commHandlers {
-			h(c)
-		}
+	g.callBefore(c)
+	if c.IsNext() {
+		arounds := append(g.inheritedArounds(), g.aroundHandlers[c.ActionLabel()]...)
+		runAround(c, arounds, func() {
+			c.tracePhase("action", func() {
+				g.callAction(c)
+			})
+		})
 	}
-	if !c.IsNext() {
-		return
-	}
-	//dest action before handlers
-	if _, ok := g.beforeHandlers[c.ActionLabel()]; ok {
-		for _, h := range g.beforeHandlers[c.ActionLabel()] {
-			h(c)
-		}
+	afters := append(g.inheritedAfters(), g.afterHandlers[c.ActionLabel()]...)
+	if len(afters) > 0 {
+		c.tracePhase("group.after", func() {
+			runHandlers(c, afters)
+		})
 	}
-	if !c.IsNext() {
+}
+
+// callBefore 按顺序执行 before handler, 遇到 AbortHandler 后停止
+func (g *Group) callBefore(c *Context) {
+	chain := g.BeforeChain(c.ActionLabel())
+	if len(chain) == 0 {
 		return
 	}
+	c.tracePhase("group.before", func() {
+		runOrdered(c, "group.before", chain)
+	})
+}
+
+func (g *Group) callAction(c *Context) {
 	//dest action handler
 	if h, ok := g.actionHandlers[c.ActionLabel()]; ok {
 		h(c)
 	} else {
-		c.Result(-1, "action not found")
-		c.AbortHandler()
+		c.Fail(comm.ErrNotFound.WithKey("error.action_not_found", "action not found"))
 		return
 	}
 }
 
+// UseBefore 注册 before handler, 执行顺序为 0, destAction 为空时对所有 action 生效
 func (g *Group) UseBefore(h HandlerFunc, destAction ...string) {
+	g.UseBeforeWithOrder(0, h, destAction...)
+}
+
+// UseBeforeWithOrder 注册 before handler, orderNum 越小越先执行, 相同时按注册顺序执行
+func (g *Group) UseBeforeWithOrder(orderNum int, h HandlerFunc, destAction ...string) {
 	if len(destAction) == 0 {
-		g.commHandlers = append(g.commHandlers, h)
+		g.commHandlers = append(g.commHandlers, newOrderedHandler(orderNum, h))
 	} else {
 		for _, action := range destAction {
 			if _, ok := g.beforeHandlers[action]; !ok {
-				g.beforeHandlers[action] = make([]HandlerFunc, 0)
+				g.beforeHandlers[action] = make([]OrderedHandler, 0)
 			}
-			g.beforeHandlers[action] = append(g.beforeHandlers[action], h)
+			g.beforeHandlers[action] = append(g.beforeHandlers[action], newOrderedHandler(orderNum, h))
 		}
 	}
 }
 
+// UseAfter 注册 after handler, action 执行完成后执行(包括被中断的请求), 可通过 c.Response 读取结果
+func (g *Group) UseAfter(h HandlerFunc, destAction ...string) {
+	if len(destAction) == 0 {
+		g.commAfters = append(g.commAfters, h)
+		return
+	}
+	for _, action := range destAction {
+		g.afterHandlers[action] = append(g.afterHandlers[action], h)
+	}
+}
+
+// UseAround 注册 around handler, 包裹 action handler 执行
+func (g *Group) UseAround(h AroundFunc, destAction ...string) {
+	if len(destAction) == 0 {
+		g.commArounds = append(g.commArounds, h)
+		return
+	}
+	for _, action := range destAction {
+		g.aroundHandlers[action] = append(g.aroundHandlers[action], h)
+	}
+}
+
+// FindBefore 仅返回指定 action 的 before handler
 func (g *Group) FindBefore(destAction string) []HandlerFunc {
 	if _, ok := g.beforeHandlers[destAction]; !ok {
 		return nil
 	}
-	return g.beforeHandlers[destAction]
+	return orderedFuncs(mergeOrdered(g.beforeHandlers[destAction]))
 }
 
-func (g *Group) BindAction(action string, h HandlerFunc) {
+// BeforeHandlers 按执行顺序返回 action 的 before handler, 包含对所有 action 生效的 handler
+func (g *Group) BeforeHandlers(destAction string) []HandlerFunc {
+	return orderedFuncs(g.BeforeChain(destAction))
+}
+
+// BeforeChain 按执行顺序返回 action 生效的 before handler
+func (g *Group) BeforeChain(destAction string) []OrderedHandler {
+	return mergeOrdered(g.inheritedBefores(), g.beforeHandlers[destAction])
+}
+
+// inheritedBefores 对所有 action 生效的 before handler, 包含上级分组
+func (g *Group) inheritedBefores() []OrderedHandler {
+	if g.parent == nil {
+		return g.commHandlers
+	}
+	return mergeOrdered(g.parent.inheritedBefores(), g.commHandlers)
+}
+
+// inheritedArounds 对所有 action 生效的 around handler, 上级分组在外层
+func (g *Group) inheritedArounds() []AroundFunc {
+	arounds := make([]AroundFunc, 0)
+	if g.parent != nil {
+		arounds = append(arounds, g.parent.inheritedArounds()...)
+	}
+	return append(arounds, g.commArounds...)
+}
+
+// inheritedAfters 对所有 action 生效的 after handler, 包含上级分组
+func (g *Group) inheritedAfters() []HandlerFunc {
+	afters := make([]HandlerFunc, 0)
+	if g.parent != nil {
+		afters = append(afters, g.parent.inheritedAfters()...)
+	}
+	return append(afters, g.commAfters...)
+}
+
+// SubGroupSeparator 子分组标签中父子分组的分隔符, 子分组标签可作为 /:role/:group/:action 路由中的单个路径段
+const SubGroupSeparator = "."
+
+// SubGroup 创建子分组, 标签为 父分组.子分组, 子分组继承父分组对所有 action 生效的 handler
+func (g *Group) SubGroup(groupLabel string) *Group {
+	label := g.groupName + SubGroupSeparator + groupLabel
+	for _, child := range g.children {
+		if child.groupName == label {
+			panic("group already exists: " + g.roleName + "/" + label)
+		}
+	}
+	child := NewGroup(g.roleName, label)
+	child.parent = g
+	g.children = append(g.children, child)
+	return child
+}
+
+func (g *Group) SubGroups() []GroupInf {
+	groups := make([]GroupInf, 0, len(g.children))
+	for _, child := range g.children {
+		groups = append(groups, child)
+	}
+	return groups
+}
+
+func (g *Group) BindAction(action string, h HandlerFunc, opts ...ActionOption) {
 	g.actionHandlers[action] = h
+	g.actionMetas[action] = newActionMeta(action, opts...)
+}
+
+func (g *Group) FindActionMeta(action string) (*ActionMeta, bool) {
+	meta, ok := g.actionMetas[action]
+	return meta, ok
+}
+
+// ActionNames 已绑定的 action 名称, 按名称排序
+func (g *Group) ActionNames() []string {
+	names := make([]string, 0, len(g.actionHandlers))
+	for name := range g.actionHandlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func (g *Group) FindAction(action string) (HandlerFunc, bool) {
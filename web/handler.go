@@ -1,3 +1,88 @@
 package web
 
+import (
+	"sort"
+)
+
 type HandlerFunc func(ctx *Context)
+
+type NextFunc func()
+
+// AroundFunc 环绕 handler, 调用 next 执行后续流程, next 返回后可通过 ctx.Response 读取或替换响应结果
+type AroundFunc func(ctx *Context, next NextFunc)
+
+// runAround 依次嵌套执行 around handler, 最内层为 final
+func runAround(c *Context, arounds []AroundFunc, final NextFunc) {
+	var call func(i int)
+	call = func(i int) {
+		if i == len(arounds) {
+			final()
+			return
+		}
+		arounds[i](c, func() {
+			call(i + 1)
+		})
+	}
+	call(0)
+}
+
+func runHandlers(c *Context, handlers []HandlerFunc) {
+	for _, h := range handlers {
+		h(c)
+	}
+}
+
+// OrderedHandler 带执行顺序的 handler, Order 越小越先执行, Order 相同时按注册顺序执行
+type OrderedHandler struct {
+	Order   int
+	Handler HandlerFunc
+}
+
+func newOrderedHandler(orderNum int, h HandlerFunc) OrderedHandler {
+	return OrderedHandler{
+		Order:   orderNum,
+		Handler: h,
+	}
+}
+
+// mergeOrdered 合并多组 handler 并按执行顺序排序, Order 相同时前面的组先执行(例如公共 handler 先于 action handler)
+func mergeOrdered(chains ...[]OrderedHandler) []OrderedHandler {
+	size := 0
+	for _, chain := range chains {
+		size += len(chain)
+	}
+	merged := make([]OrderedHandler, 0, size)
+	for _, chain := range chains {
+		merged = append(merged, chain...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Order < merged[j].Order
+	})
+	return merged
+}
+
+// runOrdered 按顺序执行 handler, 遇到 AbortHandler 后停止
+func runOrdered(c *Context, stage string, chain []OrderedHandler) {
+	for _, item := range chain {
+		if c.handlerObserver != nil {
+			c.handlerObserver(stage, HandlerName(item.Handler))
+		}
+		item.Handler(c)
+		if !c.IsNext() {
+			return
+		}
+	}
+}
+
+// ObserveHandlers 每个 before handler 执行前回调, stage 为 role.before 或 group.before, 用于测试断言
+func (c *Context) ObserveHandlers(fn func(stage, name string)) {
+	c.handlerObserver = fn
+}
+
+func orderedFuncs(chain []OrderedHandler) []HandlerFunc {
+	handlers := make([]HandlerFunc, 0, len(chain))
+	for _, item := range chain {
+		handlers = append(handlers, item.Handler)
+	}
+	return handlers
+}
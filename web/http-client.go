@@ -0,0 +1,77 @@
+package web
+
+import (
+	"catuan/components/http_client"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// InitHttpClients 根据配置创建命名的 http client, 通过 HttpClient(name) 或 http_client.Get(name) 获取
+func (a *Application) InitHttpClients() {
+	if a.appConf == nil {
+		return
+	}
+	for name, info := range a.appConf.HttpClients {
+		client, err := buildHttpClient(info)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip":  "创建http client失败",
+				"name": name,
+			}).Error(err.Error())
+			continue
+		}
+		http_client.Register(name, client)
+	}
+}
+
+// HttpClient 获取命名的 http client, 不存在时返回默认 client
+func (a *Application) HttpClient(name string) *http.Client {
+	return http_client.Get(name)
+}
+
+func buildHttpClient(info *HttpClientConfInfo) (*http.Client, error) {
+	ms := func(v int) time.Duration {
+		return time.Duration(v) * time.Millisecond
+	}
+	builder := http_client.NewBuilder().
+		Proxy(info.Proxy).
+		MaxIdleConns(info.MaxIdleConns).
+		MaxIdleConnsPerHost(info.MaxIdleConnsPerHost).
+		MaxConnsPerHost(info.MaxConnsPerHost).
+		IdleConnTimeout(ms(info.IdleConnTimeout))
+	if info.Timeout > 0 {
+		builder.Timeout(ms(info.Timeout))
+	}
+	for host, timeout := range info.HostTimeouts {
+		builder.HostTimeout(host, ms(timeout))
+	}
+	if info.Retry != nil {
+		builder.Retry(http_client.RetryPolicy{
+			MaxRetries: info.Retry.MaxRetries,
+			BaseDelay:  ms(info.Retry.BaseDelay),
+			MaxDelay:   ms(info.Retry.MaxDelay),
+		})
+	}
+	if info.Breaker != nil {
+		builder.Breaker(http_client.BreakerConfig{
+			FailureThreshold: info.Breaker.FailureThreshold,
+			OpenTimeout:      ms(info.Breaker.OpenTimeout),
+			HalfOpenRequests: info.Breaker.HalfOpenRequests,
+			SuccessThreshold: info.Breaker.SuccessThreshold,
+		})
+	}
+	logOption := http_client.LogOption{}
+	if info.Log != nil {
+		logOption.Body = info.Log.Body
+		if len(info.Log.Redact) > 0 {
+			fields := append([]string{}, http_client.DefaultRedactFields...)
+			logOption.Redactor = http_client.NewRedactor(append(fields, info.Log.Redact...)...)
+		}
+		if info.Log.Body {
+			builder.CaptureBody(info.Log.MaxBodySize)
+		}
+	}
+	builder.Hooks(http_client.LoggingHook(logOption), http_client.DefaultMetrics.Hook)
+	return builder.Build()
+}
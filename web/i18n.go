@@ -0,0 +1,250 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/i18n"
+	"errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// LocaleResolver 返回当前请求的语言, 例如从用户资料中读取, 返回空时根据 Accept-Language 选择
+type LocaleResolver func(c *Context) string
+
+// defaultMessages 框架内置消息, 配置的消息文件可覆盖
+var defaultMessages = map[string]map[string]string{
+	"zh-CN": {
+		"error.fail":                    "系统繁忙,请稍后再试",
+		"error.bad_request":             "请求参数错误",
+		"error.unauthorized":            "请先登录",
+		"error.token_expired":           "登录已过期,请重新登录",
+		"error.token_invalid":           "登录信息无效,请重新登录",
+		"error.forbidden":               "无访问权限",
+		"error.role_not_found":          "无访问权限,角色不存在",
+		"error.group_not_found":         "无访问权限,分组不存在",
+		"error.role_mismatch":           "无访问权限,角色不匹配",
+		"error.permission_required":     "无访问权限,需要权限 {permission}",
+		"error.permission_read_failed":  "读取用户权限失败",
+		"error.not_found":               "资源不存在",
+		"error.action_not_found":        "接口不存在",
+		"error.not_acceptable":          "不支持请求的响应格式",
+		"error.conflict":                "请求正在处理中,请勿重复提交",
+		"error.idempotency_key_missing": "缺少请求头 {header}",
+		"error.too_many_requests":       "请求过于频繁,请稍后再试",
+		"error.timeout":                 "请求超时",
+		"upload.invalid_form":           "文件过大或表单格式错误",
+		"upload.no_file":                "请选择文件",
+		"upload.too_many_files":         "文件数量超出限制",
+		"upload.too_large":              "文件大小超出限制: {filename}",
+		"upload.type_not_allowed":       "不支持的文件类型: {filename}",
+		"upload.save_failed":            "保存文件失败",
+		"file.not_found":                "文件不存在",
+		"file.invalid_key":              "文件路径不合法",
+		"validation.required":           "{field}不能为空",
+		"validation.min":                "{field}不能小于{param}",
+		"validation.max":                "{field}不能大于{param}",
+		"validation.len":                "{field}长度必须为{param}",
+		"validation.gte":                "{field}不能小于{param}",
+		"validation.lte":                "{field}不能大于{param}",
+		"validation.gt":                 "{field}必须大于{param}",
+		"validation.lt":                 "{field}必须小于{param}",
+		"validation.email":              "{field}必须是有效的邮箱",
+		"validation.url":                "{field}必须是有效的网址",
+		"validation.oneof":              "{field}必须是[{param}]中的一个",
+		"validation.numeric":            "{field}必须是数字",
+		"validation.default":            "{field}格式不正确",
+	},
+	"en": {
+		"error.fail":                    "The system is busy, please try again later",
+		"error.bad_request":             "Invalid request parameters",
+		"error.unauthorized":            "Please log in first",
+		"error.token_expired":           "Your login has expired, please log in again",
+		"error.token_invalid":           "Invalid login, please log in again",
+		"error.forbidden":               "Access denied",
+		"error.role_not_found":          "Access denied, role not found",
+		"error.group_not_found":         "Access denied, group not found",
+		"error.role_mismatch":           "Access denied, role mismatch",
+		"error.permission_required":     "Access denied, permission required: {permission}",
+		"error.permission_read_failed":  "Failed to read user permissions",
+		"error.not_found":               "Not found",
+		"error.action_not_found":        "Action not found",
+		"error.not_acceptable":          "Requested response format is not supported",
+		"error.conflict":                "The request is being processed, please do not resubmit",
+		"error.idempotency_key_missing": "Missing request header {header}",
+		"error.too_many_requests":       "Too many requests, please try again later",
+		"error.timeout":                 "Request timed out",
+		"upload.invalid_form":           "File too large or invalid form",
+		"upload.no_file":                "Please choose a file",
+		"upload.too_many_files":         "Too many files",
+		"upload.too_large":              "File too large: {filename}",
+		"upload.type_not_allowed":       "File type not allowed: {filename}",
+		"upload.save_failed":            "Failed to save file",
+		"file.not_found":                "File not found",
+		"file.invalid_key":              "Invalid file path",
+		"validation.required":           "{field} is required",
+		"validation.min":                "{field} must be at least {param}",
+		"validation.max":                "{field} must be at most {param}",
+		"validation.len":                "{field} must have length {param}",
+		"validation.gte":                "{field} must be at least {param}",
+		"validation.lte":                "{field} must be at most {param}",
+		"validation.gt":                 "{field} must be greater than {param}",
+		"validation.lt":                 "{field} must be less than {param}",
+		"validation.email":              "{field} must be a valid email",
+		"validation.url":                "{field} must be a valid url",
+		"validation.oneof":              "{field} must be one of [{param}]",
+		"validation.numeric":            "{field} must be numeric",
+		"validation.default":            "{field} is invalid",
+	},
+}
+
+var jsonFieldNamesOnce sync.Once
+
+// UseJSONFieldNames 校验错误中的字段名使用 json tag(其次 form tag), 与客户端提交的字段一致
+// 修改的是 gin 全局的 binding.Validator, 配置 i18n 时由 InitI18n 调用, 未配置时按需调用
+func UseJSONFieldNames() {
+	jsonFieldNamesOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				name, _, _ = strings.Cut(field.Tag.Get("form"), ",")
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	})
+}
+
+// NewI18nBundle 创建包含框架内置消息的 Bundle
+func NewI18nBundle(defaultLocale string) *i18n.Bundle {
+	b := i18n.NewBundle(defaultLocale)
+	for locale, messages := range defaultMessages {
+		b.AddMessages(locale, messages)
+	}
+	return b
+}
+
+// InitI18n 加载配置目录下的消息文件
+func (a *Application) InitI18n() {
+	if a.appConf == nil || a.appConf.I18n == nil {
+		return
+	}
+	info := a.appConf.I18n
+	UseJSONFieldNames()
+	if info.Default == "" {
+		info.Default = "zh-CN"
+	}
+	b := NewI18nBundle(info.Default)
+	if info.Dir != "" {
+		if err := b.LoadDir(info.Dir); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "加载多语言消息失败",
+				"dir": info.Dir,
+			}).Error(err.Error())
+		}
+	}
+	a.i18n = b
+}
+
+// I18n 未配置时使用仅包含内置消息的 Bundle, 默认语言 zh-CN
+func (a *Application) I18n() *i18n.Bundle {
+	a.i18nOnce.Do(func() {
+		if a.i18n == nil {
+			a.i18n = NewI18nBundle("zh-CN")
+		}
+	})
+	return a.i18n
+}
+
+// SetI18n 使用自定义的 Bundle, 可通过 NewI18nBundle 创建以保留内置消息
+func (a *Application) SetI18n(b *i18n.Bundle) {
+	a.i18n = b
+}
+
+// UseLocaleResolver 设置语言解析, 优先于 Accept-Language
+func (a *Application) UseLocaleResolver(resolver LocaleResolver) {
+	a.localeResolver = resolver
+}
+
+// Locale 当前请求的语言
+func (c *Context) Locale() string {
+	c.localeOnce.Do(func() {
+		if c.app == nil {
+			return
+		}
+		b := c.app.I18n()
+		if c.app.localeResolver != nil {
+			if locale := c.app.localeResolver(c); locale != "" && b.Has(locale) {
+				c.locale = locale
+				return
+			}
+		}
+		c.locale = b.Match(c.GetHeader("Accept-Language"))
+	})
+	return c.locale
+}
+
+// T 按当前请求的语言翻译, 不存在时返回 key
+func (c *Context) T(key string, args ...map[string]any) string {
+	if c.app == nil {
+		return key
+	}
+	var params map[string]any
+	if len(args) > 0 {
+		params = args[0]
+	}
+	return c.app.I18n().T(c.Locale(), key, params)
+}
+
+// localize 翻译响应消息, 返回副本, 不修改原响应
+func (c *Context) localize(resp *comm.RespResult) *comm.RespResult {
+	if resp.Key == "" && len(resp.Args) == 0 {
+		return resp
+	}
+	translated := *resp
+	if resp.Key != "" && c.app != nil {
+		if message, ok := c.app.I18n().Translate(c.Locale(), resp.Key, resp.Args); ok {
+			translated.ErrMsg = message
+			return &translated
+		}
+	}
+	translated.ErrMsg = i18n.Format(resp.ErrMsg, resp.Args)
+	return &translated
+}
+
+// ValidationError 将参数绑定错误转换为 comm.ErrBadRequest, 校验错误按当前语言翻译
+// 字段名默认为结构体字段名, 调用 UseJSONFieldNames 后使用 json tag
+func (c *Context) ValidationError(err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return comm.ErrBadRequest.Wrap(err)
+	}
+	messages := make([]string, 0, len(verrs))
+	for _, fe := range verrs {
+		field := fe.Field()
+		fieldKey := "field." + field
+		if name := c.T(fieldKey); name != fieldKey {
+			field = name
+		}
+		args := map[string]any{"field": field, "param": fe.Param()}
+		key := "validation." + fe.Tag()
+		message := c.T(key, args)
+		if message == key {
+			message = c.T("validation.default", args)
+		}
+		messages = append(messages, message)
+	}
+	return comm.ErrBadRequest.Wrap(err).WithMessage(strings.Join(messages, "; "))
+}
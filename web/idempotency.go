@@ -0,0 +1,105 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/idempotency"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// IdempotencyOption 幂等处理配置
+type IdempotencyOption struct {
+	Store    idempotency.Store
+	Header   string        // 幂等键请求头, 默认 Idempotency-Key
+	TTL      time.Duration // 处理结果保存时间, 默认 24 小时
+	LockTTL  time.Duration // 处理中状态的最长保留时间, 需大于 action 超时时间, 默认 1 分钟
+	Wait     time.Duration // 重复请求等待首个请求完成的时间, 0 为直接返回冲突
+	Required bool          // 是否必须携带幂等键
+}
+
+// Idempotency 幂等 around handler, 相同幂等键的请求只执行一次, 之后的请求直接返回首次的结果
+func Idempotency(opt IdempotencyOption) AroundFunc {
+	if opt.Header == "" {
+		opt.Header = "Idempotency-Key"
+	}
+	if opt.TTL <= 0 {
+		opt.TTL = time.Hour * 24
+	}
+	if opt.LockTTL <= 0 {
+		opt.LockTTL = time.Minute
+	}
+	return func(c *Context, next NextFunc) {
+		idemKey := c.GetHeader(opt.Header)
+		if idemKey == "" {
+			if opt.Required {
+				c.Fail(comm.ErrBadRequest.
+					WithKey("error.idempotency_key_missing", "缺少请求头 {header}").
+					WithArgs(map[string]any{"header": opt.Header}))
+				return
+			}
+			next()
+			return
+		}
+		key := c.RoleLabel() + ":" + c.GroupLabel() + ":" + c.ActionLabel() + ":" + c.UserID() + ":" + idemKey
+		ctx := c.Request.Context()
+		rec, acquired, err := opt.Store.Begin(ctx, key, opt.LockTTL)
+		// 首个请求处理中时等待, 超过 Wait 或请求结束后返回冲突
+		wait := time.NewTimer(opt.Wait)
+		defer wait.Stop()
+		for waiting := true; waiting && err == nil && !acquired && rec.Status == idempotency.StatusPending; {
+			select {
+			case <-ctx.Done():
+				waiting = false
+			case <-wait.C:
+				waiting = false
+			case <-time.After(time.Millisecond * 100):
+				rec, acquired, err = opt.Store.Begin(ctx, key, opt.LockTTL)
+			}
+		}
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "幂等记录读取异常",
+				"key": key,
+			}).Error(err.Error())
+			c.Fail(comm.ErrFail)
+			return
+		}
+		if !acquired {
+			if resp := rec.Response(); rec.Status == idempotency.StatusDone && resp != nil {
+				c.Header("Idempotent-Replayed", "true")
+				c.SetResponse(resp)
+				return
+			}
+			c.Fail(comm.ErrConflict)
+			return
+		}
+
+		token := rec.Token
+		completed := false
+		defer func() {
+			// 未完成(含 panic 及服务端错误)时释放, 允许客户端重试
+			if !completed {
+				if err := opt.Store.Release(ctx, key, token); err != nil {
+					logrus.WithFields(logrus.Fields{
+						"tip": "幂等记录释放异常",
+						"key": key,
+					}).Error(err.Error())
+				}
+			}
+		}()
+		next()
+		resp := c.Response()
+		if resp == nil || respStatus(resp) >= http.StatusInternalServerError {
+			return
+		}
+		if err := opt.Store.Complete(ctx, key, token, resp, opt.TTL); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "幂等记录保存异常",
+				"key": key,
+			}).Error(err.Error())
+			return
+		}
+		completed = true
+	}
+}
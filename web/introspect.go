@@ -0,0 +1,133 @@
+package web
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// RoleDesc 角色描述
+type RoleDesc struct {
+	Label  string      `json:"label"`
+	Before []string    `json:"before"`
+	Groups []GroupDesc `json:"groups"`
+}
+
+// GroupDesc 分组描述
+type GroupDesc struct {
+	Label   string       `json:"label"`
+	Actions []ActionDesc `json:"actions"`
+}
+
+// ActionDesc action 描述, Before 为分组中对该 action 生效的 before handler
+type ActionDesc struct {
+	Label       string   `json:"label"`
+	Summary     string   `json:"summary,omitempty"`
+	Before      []string `json:"before"`
+	Permissions []string `json:"permissions,omitempty"`
+	Request     *Schema  `json:"request,omitempty"`
+	Response    *Schema  `json:"response,omitempty"`
+	Stream      bool     `json:"stream,omitempty"`
+	WebSocket   bool     `json:"websocket,omitempty"`
+}
+
+// Describe 列出已注册的所有 role/group/action, 按名称排序
+func (a *Application) Describe() []RoleDesc {
+	roleLabels := make([]string, 0, len(a.roles)+len(a.groups))
+	for label := range a.roles {
+		roleLabels = append(roleLabels, label)
+	}
+	for label := range a.groups {
+		if _, ok := a.roles[label]; !ok {
+			roleLabels = append(roleLabels, label)
+		}
+	}
+	sort.Strings(roleLabels)
+
+	roles := make([]RoleDesc, 0, len(roleLabels))
+	for _, roleLabel := range roleLabels {
+		roleDesc := RoleDesc{Label: roleLabel, Before: []string{}, Groups: []GroupDesc{}}
+		if role, ok := a.roles[roleLabel]; ok {
+			roleDesc.Before = handlerNames(role.FindBefore())
+		}
+		groupLabels := make([]string, 0, len(a.groups[roleLabel]))
+		for label := range a.groups[roleLabel] {
+			groupLabels = append(groupLabels, label)
+		}
+		sort.Strings(groupLabels)
+		for _, label := range groupLabels {
+			roleDesc.Groups = append(roleDesc.Groups, describeGroup(a.groups[roleLabel][label]))
+		}
+		roles = append(roles, roleDesc)
+	}
+	return roles
+}
+
+func describeGroup(group GroupInf) GroupDesc {
+	groupDesc := GroupDesc{Label: group.GroupLabel(), Actions: []ActionDesc{}}
+	for _, action := range group.ActionNames() {
+		actionDesc := ActionDesc{
+			Label:  action,
+			Before: handlerNames(group.BeforeHandlers(action)),
+		}
+		if meta, ok := group.FindActionMeta(action); ok {
+			actionDesc.Summary = meta.Summary
+			actionDesc.Permissions = meta.Permissions
+			actionDesc.Request = SchemaOf(meta.Request)
+			actionDesc.Response = SchemaOf(meta.Response)
+			actionDesc.Stream = meta.Stream
+			actionDesc.WebSocket = meta.WebSocket
+		}
+		groupDesc.Actions = append(groupDesc.Actions, actionDesc)
+	}
+	return groupDesc
+}
+
+// HandlerName 返回 handler 的函数名, 匿名函数形如 pkg.Func.func1
+func HandlerName(h HandlerFunc) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	return fn.Name()
+}
+
+func handlerNames(handlers []HandlerFunc) []string {
+	names := make([]string, 0, len(handlers))
+	for _, h := range handlers {
+		names = append(names, HandlerName(h))
+	}
+	return names
+}
+
+// ChainEntry 处理链中的一个 handler
+type ChainEntry struct {
+	Level string `json:"level"` // role/group/action
+	Order int    `json:"order"`
+	Name  string `json:"name"`
+}
+
+// HandlerChain 返回请求指定 action 时 before handler 与 action handler 的实际执行顺序
+func (a *Application) HandlerChain(roleLabel, groupLabel, actionLabel string) ([]ChainEntry, bool) {
+	role, ok := a.FindRole(roleLabel)
+	if !ok {
+		return nil, false
+	}
+	group, ok := a.FindGroup(roleLabel, groupLabel)
+	if !ok {
+		return nil, false
+	}
+	action, ok := group.FindAction(actionLabel)
+	if !ok {
+		return nil, false
+	}
+	chain := make([]ChainEntry, 0)
+	for _, item := range role.BeforeChain() {
+		chain = append(chain, ChainEntry{Level: "role", Order: item.Order, Name: HandlerName(item.Handler)})
+	}
+	for _, item := range group.BeforeChain(actionLabel) {
+		chain = append(chain, ChainEntry{Level: "group", Order: item.Order, Name: HandlerName(item.Handler)})
+	}
+	chain = append(chain, ChainEntry{Level: "action", Name: HandlerName(action)})
+	return chain, true
+}
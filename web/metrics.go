@@ -0,0 +1,198 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/http_client"
+	"catuan/components/metrics"
+	"github.com/gin-gonic/gin"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// actionMetrics action 请求指标
+type actionMetrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+	timeouts *metrics.Counter
+	panics   *metrics.Counter
+}
+
+// Metrics 当前应用的指标注册表, 自定义指标通过 metrics.NewCounter/NewGauge 等注册到 metrics.Default 后一同输出
+// 每个应用使用独立的注册表, 同一进程内创建多个应用(例如测试)时不会重复输出连接池等指标
+func (a *Application) Metrics() *metrics.Registry {
+	a.actionMetrics()
+	return a.metricsRegistry
+}
+
+func (a *Application) actionMetrics() *actionMetrics {
+	a.metricsOnce.Do(func() {
+		r := metrics.NewRegistry()
+		a.metricsRegistry = r
+		a.metrics = &actionMetrics{
+			requests: r.Counter("catuan_requests_total", "Total action requests by response err_code.", "role", "group", "action", "code"),
+			duration: r.Histogram("catuan_request_duration_seconds", "Action request latency in seconds.", nil, "role", "group", "action"),
+			timeouts: r.Counter("catuan_request_timeouts_total", "Total action requests that timed out.", "role", "group", "action"),
+			panics:   r.Counter("catuan_request_panics_total", "Total panics while handling action requests.", "role", "group", "action"),
+		}
+		r.RegisterCollector(a.collectDBStats)
+		r.RegisterCollector(a.collectRedisStats)
+		r.RegisterCollector(collectHttpClientStats)
+		r.RegisterCollector(metrics.Default.Gather)
+	})
+	return a.metrics
+}
+
+// InitMetrics 根据配置注册指标拉取地址
+func (a *Application) InitMetrics() {
+	if a.appConf == nil || a.appConf.Metrics == nil {
+		return
+	}
+	path := a.appConf.Metrics.Path
+	if path == "" {
+		path = "/metrics"
+	}
+	a.ServeMetrics(path)
+}
+
+// ServeMetrics 注册 prometheus 文本格式的指标路由
+func (a *Application) ServeMetrics(path string) {
+	a.GET(path, gin.WrapH(a.Metrics().Handler()))
+}
+
+// routeLabels 未注册的 action 统一记为 unknown, 避免任意路径产生大量标签
+func routeLabels(c *Context) []string {
+	if _, ok := c.ActionMeta(); !ok {
+		return []string{"unknown", "unknown", "unknown"}
+	}
+	return []string{c.RoleLabel(), c.GroupLabel(), c.ActionLabel()}
+}
+
+// finishRequest 响应写入后记录指标并结束请求 span
+func (a *Application) finishRequest(c *Context, start time.Time, resp *comm.RespResult, timeout bool) {
+	a.observeRequest(c, start, resp, timeout)
+	a.endTrace(c, resp, timeout)
+}
+
+func (a *Application) observeRequest(c *Context, start time.Time, resp *comm.RespResult, timeout bool) {
+	m := a.actionMetrics()
+	labels := routeLabels(c)
+	code := comm.ErrCodeSuccess
+	if resp != nil {
+		code = resp.ErrCode
+	}
+	m.requests.Inc(append(labels, strconv.Itoa(code))...)
+	m.duration.Observe(time.Since(start).Seconds(), labels...)
+	if timeout {
+		m.timeouts.Inc(labels...)
+	}
+}
+
+func (a *Application) observePanic(c *Context) {
+	a.actionMetrics().panics.Inc(routeLabels(c)...)
+}
+
+func gaugeFamily(name, help string) metrics.Family {
+	return metrics.Family{Name: name, Help: help, Type: metrics.TypeGauge}
+}
+
+func counterFamily(name, help string) metrics.Family {
+	return metrics.Family{Name: name, Help: help, Type: metrics.TypeCounter}
+}
+
+// collectDBStats 数据库连接池指标, db 标签为 cdbChain 下标
+func (a *Application) collectDBStats() []metrics.Family {
+	families := []metrics.Family{
+		gaugeFamily("catuan_db_max_open_connections", "Maximum number of open connections to the database."),
+		gaugeFamily("catuan_db_open_connections", "The number of established connections both in use and idle."),
+		gaugeFamily("catuan_db_in_use_connections", "The number of connections currently in use."),
+		gaugeFamily("catuan_db_idle_connections", "The number of idle connections."),
+		counterFamily("catuan_db_wait_count_total", "The total number of connections waited for."),
+		counterFamily("catuan_db_wait_duration_seconds_total", "The total time blocked waiting for a new connection."),
+		counterFamily("catuan_db_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns."),
+		counterFamily("catuan_db_max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime."),
+		counterFamily("catuan_db_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime."),
+	}
+	for i, db := range a.cdbChain {
+		if db == nil {
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			continue
+		}
+		stats := sqlDB.Stats()
+		labels := map[string]string{"db": strconv.Itoa(i)}
+		values := []float64{
+			float64(stats.MaxOpenConnections),
+			float64(stats.OpenConnections),
+			float64(stats.InUse),
+			float64(stats.Idle),
+			float64(stats.WaitCount),
+			stats.WaitDuration.Seconds(),
+			float64(stats.MaxIdleClosed),
+			float64(stats.MaxIdleTimeClosed),
+			float64(stats.MaxLifetimeClosed),
+		}
+		for j, v := range values {
+			families[j].Samples = append(families[j].Samples, metrics.Sample{Labels: labels, Value: v})
+		}
+	}
+	return families
+}
+
+// collectRedisStats redis 连接池指标, redis 标签为 credisChain 下标
+func (a *Application) collectRedisStats() []metrics.Family {
+	families := []metrics.Family{
+		counterFamily("catuan_redis_pool_hits_total", "Number of times a free connection was found in the pool."),
+		counterFamily("catuan_redis_pool_misses_total", "Number of times a free connection was not found in the pool."),
+		counterFamily("catuan_redis_pool_timeouts_total", "Number of times a wait timeout occurred."),
+		gaugeFamily("catuan_redis_pool_total_connections", "Number of total connections in the pool."),
+		gaugeFamily("catuan_redis_pool_idle_connections", "Number of idle connections in the pool."),
+		counterFamily("catuan_redis_pool_stale_connections_total", "Number of stale connections removed from the pool."),
+	}
+	for i, client := range a.credisChain {
+		if client == nil {
+			continue
+		}
+		stats := client.PoolStats()
+		labels := map[string]string{"redis": strconv.Itoa(i)}
+		values := []float64{
+			float64(stats.Hits),
+			float64(stats.Misses),
+			float64(stats.Timeouts),
+			float64(stats.TotalConns),
+			float64(stats.IdleConns),
+			float64(stats.StaleConns),
+		}
+		for j, v := range values {
+			families[j].Samples = append(families[j].Samples, metrics.Sample{Labels: labels, Value: v})
+		}
+	}
+	return families
+}
+
+// collectHttpClientStats 默认 http 客户端按 host 统计的请求指标
+func collectHttpClientStats() []metrics.Family {
+	requests := counterFamily("catuan_http_client_requests_total", "Total outgoing http requests by host.")
+	errors := counterFamily("catuan_http_client_errors_total", "Total failed outgoing http requests by host.")
+	duration := metrics.Family{Name: "catuan_http_client_request_duration_seconds", Help: "Outgoing http request latency in seconds.", Type: metrics.TypeHistogram}
+	snapshot := http_client.DefaultMetrics.Snapshot()
+	hosts := make([]string, 0, len(snapshot))
+	for host := range snapshot {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		stat := snapshot[host]
+		labels := map[string]string{"host": host}
+		requests.Samples = append(requests.Samples, metrics.Sample{Labels: labels, Value: float64(stat.Requests)})
+		errors.Samples = append(errors.Samples, metrics.Sample{Labels: labels, Value: float64(stat.Errors)})
+		buckets := make(map[float64]uint64, len(stat.Buckets))
+		for i, bound := range stat.Buckets {
+			buckets[bound] = stat.BucketCounts[i]
+		}
+		duration.Samples = append(duration.Samples, metrics.Sample{Labels: labels, Value: stat.LatencySum, Count: stat.Requests, Buckets: buckets})
+	}
+	return []metrics.Family{requests, errors, duration}
+}
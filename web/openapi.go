@@ -0,0 +1,204 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// OpenAPIOption 接口文档生成参数
+type OpenAPIOption struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+	PathPattern string // 路由格式, 默认 /{role}/{group}/{action}
+	Method      string // 请求方式, 默认 POST
+}
+
+type OpenAPIDoc struct {
+	OpenAPI string                          `json:"openapi"`
+	Info    OpenAPIInfo                     `json:"info"`
+	Servers []OpenAPIServer                 `json:"servers,omitempty"`
+	Tags    []OpenAPITag                    `json:"tags,omitempty"`
+	Paths   map[string]map[string]Operation `json:"paths"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIServer struct {
+	Url string `json:"url"`
+}
+
+type OpenAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	OperationId string              `json:"operationId"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// OpenAPI 根据已注册的 role/group/action 生成 OpenAPI 3 文档
+func (a *Application) OpenAPI(opt OpenAPIOption) *OpenAPIDoc {
+	if opt.Title == "" {
+		opt.Title = "catuan api"
+	}
+	if opt.Version == "" {
+		opt.Version = a.version
+	}
+	if opt.PathPattern == "" {
+		opt.PathPattern = "/{role}/{group}/{action}"
+	}
+	method := strings.ToLower(opt.Method)
+	if method == "" {
+		method = "post"
+	}
+	doc := &OpenAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       opt.Title,
+			Version:     opt.Version,
+			Description: opt.Description,
+		},
+		Paths: make(map[string]map[string]Operation),
+	}
+	for _, server := range opt.Servers {
+		doc.Servers = append(doc.Servers, OpenAPIServer{Url: server})
+	}
+	for _, role := range a.Describe() {
+		tag := OpenAPITag{Name: role.Label}
+		if len(role.Before) > 0 {
+			tag.Description = "before: " + strings.Join(role.Before, ", ")
+		}
+		doc.Tags = append(doc.Tags, tag)
+		for _, group := range role.Groups {
+			for _, action := range group.Actions {
+				path := strings.NewReplacer(
+					"{role}", role.Label,
+					"{group}", group.Label,
+					"{action}", action.Label,
+				).Replace(opt.PathPattern)
+				doc.Paths[path] = map[string]Operation{
+					method: buildOperation(role.Label, group.Label, action, method),
+				}
+			}
+		}
+	}
+	return doc
+}
+
+func buildOperation(role, group string, action ActionDesc, method string) Operation {
+	op := Operation{
+		Tags:        []string{role},
+		Summary:     action.Summary,
+		OperationId: role + "." + group + "." + action.Label,
+		Responses: map[string]Response{
+			"200": {
+				Description: "success",
+				Content: map[string]MediaType{
+					"application/json": {Schema: respResultSchema(action.Response)},
+				},
+			},
+		},
+	}
+	if action.Stream {
+		schema := action.Response
+		if schema == nil {
+			schema = &Schema{}
+		}
+		op.Responses["200"] = Response{
+			Description: "stream",
+			Content: map[string]MediaType{
+				"text/event-stream":    {Schema: schema},
+				"application/x-ndjson": {Schema: schema},
+			},
+		}
+	}
+	if len(action.Before) > 0 {
+		op.Description = "before: " + strings.Join(action.Before, ", ")
+	}
+	if action.Request != nil && method != "get" {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: action.Request},
+			},
+		}
+	}
+	return op
+}
+
+// respResultSchema comm.RespResult 外层结构
+func respResultSchema(data *Schema) *Schema {
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"err_code": {Type: "integer"},
+			"message":  {Type: "string"},
+		},
+		Required: []string{"err_code", "message"},
+	}
+	if data != nil {
+		s.Properties["data"] = data
+	}
+	return s
+}
+
+// ServeOpenAPI 注册接口文档路由, 每次请求时重新生成
+func (a *Application) ServeOpenAPI(path string, opt OpenAPIOption) {
+	a.GET(path, func(c *gin.Context) {
+		c.JSON(http.StatusOK, a.OpenAPI(opt))
+	})
+}
+
+// ServeSwaggerUI 注册 Swagger UI 页面, docPath 为 ServeOpenAPI 注册的路径
+func (a *Application) ServeSwaggerUI(path string, docPath string) {
+	page := strings.ReplaceAll(swaggerUIPage, "{{docPath}}", docPath)
+	a.GET(path, func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	})
+}
+
+// swaggerUIPage 固定 swagger-ui-dist 版本并校验 SRI, 升级时需同时更新 integrity
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8"/>
+  <title>API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5.18.2/swagger-ui.css"
+        integrity="sha384-rcbEi6xgdPk0iWkAQzT2F3FeBJXdG+ydrawGlfHAFIZG7wU6aKbQaRewysYpmrlW" crossorigin="anonymous"/>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5.18.2/swagger-ui-bundle.js"
+        integrity="sha384-NXtFPpN61oWCuN4D42K6Zd5Rt2+uxeIT36R7kpXBuY9tLnZorzrJ4ykpqwJfgjpZ" crossorigin="anonymous"></script>
+<script>
+  window.ui = SwaggerUIBundle({url: "{{docPath}}", dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>
+`
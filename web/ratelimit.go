@@ -0,0 +1,177 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/limits"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RateByRole   = "role"
+	RateByGroup  = "group"
+	RateByAction = "action"
+	RateByIP     = "ip"
+	RateByUser   = "user"
+)
+
+// RateLimitRule 限流规则, Role/Group/Action 为空时匹配全部
+type RateLimitRule struct {
+	Name    string
+	Role    string
+	Group   string
+	Action  string
+	By      []string // 限流维度 RateByRole 等, 为空时所有匹配的请求共享限额
+	Limiter limits.RateLimiter
+}
+
+func (r *RateLimitRule) match(c *Context) bool {
+	return (r.Role == "" || r.Role == c.RoleLabel()) &&
+		(r.Group == "" || r.Group == c.GroupLabel()) &&
+		(r.Action == "" || r.Action == c.ActionLabel())
+}
+
+func (r *RateLimitRule) key(c *Context) string {
+	parts := []string{r.Name}
+	for _, by := range r.By {
+		switch by {
+		case RateByRole:
+			parts = append(parts, c.RoleLabel())
+		case RateByGroup:
+			parts = append(parts, c.GroupLabel())
+		case RateByAction:
+			parts = append(parts, c.ActionLabel())
+		case RateByIP:
+			parts = append(parts, "ip:"+c.ClientIP())
+		case RateByUser:
+			// 未登录时按 ip 限流
+			if userID := c.UserID(); userID != "" {
+				parts = append(parts, "user:"+userID)
+			} else {
+				parts = append(parts, "ip:"+c.ClientIP())
+			}
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// allow 检查限流, 超出时设置 Retry-After 并返回错误
+func (r *RateLimitRule) allow(c *Context) bool {
+	ok, wait, err := r.Limiter.Allow(c.Request.Context(), r.key(c))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"tip":  "限流检查异常",
+			"rule": r.Name,
+		}).Error(err.Error())
+	}
+	if ok {
+		return true
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.Fail(comm.ErrTooManyReqs)
+	return false
+}
+
+// RateLimit 限流 before handler, 可注册到指定的角色/分组/action
+func RateLimit(rule *RateLimitRule) HandlerFunc {
+	return func(c *Context) {
+		if rule.match(c) {
+			rule.allow(c)
+		}
+	}
+}
+
+// UseRateLimit 注册全局限流规则, 在角色 before handler(鉴权)之后, 分组 handler 之前检查
+func (a *Application) UseRateLimit(rules ...*RateLimitRule) {
+	a.rateLimits = append(a.rateLimits, rules...)
+}
+
+func (a *Application) checkRateLimit(c *Context) bool {
+	for _, rule := range a.rateLimits {
+		if rule.match(c) && !rule.allow(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (info *RateLimitInfo) validate() error {
+	switch info.Algorithm {
+	case "", limits.AlgorithmTokenBucket, limits.AlgorithmSlidingWindow:
+	default:
+		return errors.New("unknown algorithm: " + info.Algorithm)
+	}
+	switch info.Backend {
+	case "", "local", "redis":
+	default:
+		return errors.New("unknown backend: " + info.Backend)
+	}
+	for _, by := range info.By {
+		switch by {
+		case RateByRole, RateByGroup, RateByAction, RateByIP, RateByUser:
+		default:
+			return errors.New("unknown by: " + by)
+		}
+	}
+	if info.Limit <= 0 || info.Window <= 0 {
+		return errors.New("limit and window must be positive")
+	}
+	return nil
+}
+
+// InitRateLimit 根据配置创建限流规则
+func (a *Application) InitRateLimit() {
+	if a.appConf == nil {
+		return
+	}
+	for i, info := range a.appConf.RateLimit {
+		name := info.Name
+		if name == "" {
+			name = "rate" + strconv.Itoa(i)
+		}
+		// 加载配置时已校验, 此处校验直接设置的配置
+		if err := info.validate(); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip":  "限流规则配置错误",
+				"rule": name,
+			}).Error(err.Error())
+			continue
+		}
+		window := time.Duration(info.Window) * time.Second
+		var limiter limits.RateLimiter
+		if info.Backend == "redis" {
+			rdb := a.GetRedis(info.Redis)
+			if rdb == nil {
+				logrus.WithFields(logrus.Fields{
+					"tip":  "限流使用的redis不存在",
+					"rule": name,
+				}).Error("rate limit init failed")
+				continue
+			}
+			prefix := "ratelimit:"
+			if info.Algorithm == limits.AlgorithmSlidingWindow {
+				limiter = limits.NewRedisSlidingWindow(rdb, prefix, info.Limit, window)
+			} else {
+				limiter = limits.NewRedisTokenBucket(rdb, prefix, info.Limit, window)
+			}
+		} else {
+			if info.Algorithm == limits.AlgorithmSlidingWindow {
+				limiter = limits.NewSlidingWindow(info.Limit, window)
+			} else {
+				limiter = limits.NewTokenBucket(info.Limit, window)
+			}
+		}
+		a.UseRateLimit(&RateLimitRule{
+			Name:    name,
+			Role:    info.Role,
+			Group:   info.Group,
+			Action:  info.Action,
+			By:      info.By,
+			Limiter: limiter,
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/rbac"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PermissionOrder 权限校验 handler 的执行顺序, 在鉴权之后执行
+const PermissionOrder = AuthOrder + 100
+
+// RequirePermission 校验当前用户是否拥有 action 通过 WithPermissions 声明的权限, p 为 nil 时使用 Application.RBAC
+func RequirePermission(p rbac.Provider) HandlerFunc {
+	return func(c *Context) {
+		meta, ok := c.ActionMeta()
+		if !ok || len(meta.Permissions) == 0 {
+			return
+		}
+		provider := p
+		if provider == nil && c.app != nil {
+			provider = c.app.RBAC()
+		}
+		if provider == nil {
+			logrus.WithFields(logrus.Fields{
+				"tip":    "未配置权限提供者",
+				"role":   c.RoleLabel(),
+				"group":  c.GroupLabel(),
+				"action": c.ActionLabel(),
+			}).Error("rbac provider not found")
+			c.Fail(comm.ErrForbidden)
+			return
+		}
+		userID := c.UserID()
+		if userID == "" {
+			c.Fail(comm.ErrUnauthorized)
+			return
+		}
+		perms, err := provider.Permissions(c.Request.Context(), userID)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip":    "读取用户权限异常",
+				"userId": userID,
+			}).Error(err.Error())
+			c.Fail(comm.ErrFail.WithKey("error.permission_read_failed", "读取用户权限失败"))
+			return
+		}
+		for _, required := range meta.Permissions {
+			if !rbac.Match(perms, required) {
+				c.Fail(comm.ErrForbidden.
+					WithKey("error.permission_required", "access denied,permission required: {permission}").
+					WithArgs(map[string]any{"permission": required}))
+				return
+			}
+		}
+	}
+}
+
+// RequirePermission 角色下所有 action 校验声明的权限
+func (r *Role) RequirePermission(p rbac.Provider) {
+	r.UseBefore(PermissionOrder, RequirePermission(p))
+}
+
+// ActionRef action 路径
+type ActionRef struct {
+	Role   string `json:"role"`
+	Group  string `json:"group"`
+	Action string `json:"action"`
+}
+
+func (r ActionRef) String() string {
+	return strings.Join([]string{r.Role, r.Group, r.Action}, "/")
+}
+
+// ActionsByPermission 列出需要 perm 权限的 action, 支持 order:* 通配, action 可能还需要其他权限
+func (a *Application) ActionsByPermission(perm string) []ActionRef {
+	granted := []string{perm}
+	refs := make([]ActionRef, 0)
+	for roleLabel, groups := range a.groups {
+		for groupLabel, group := range groups {
+			for _, action := range group.ActionNames() {
+				meta, ok := group.FindActionMeta(action)
+				if !ok {
+					continue
+				}
+				for _, required := range meta.Permissions {
+					if rbac.Match(granted, required) {
+						refs = append(refs, ActionRef{Role: roleLabel, Group: groupLabel, Action: action})
+						break
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].String() < refs[j].String()
+	})
+	return refs
+}
+
+// permissionHandlerPC RequirePermission 返回的 handler 共用同一函数入口, 用于识别已注册的权限校验
+var permissionHandlerPC = reflect.ValueOf(RequirePermission(nil)).Pointer()
+
+// UnguardedActions 声明了权限但未注册 RequirePermission 的 action, 这些 action 的权限不会被校验
+func (a *Application) UnguardedActions() []ActionRef {
+	refs := make([]ActionRef, 0)
+	for roleLabel, groups := range a.groups {
+		role, hasRole := a.roles[roleLabel]
+		for groupLabel, group := range groups {
+			for _, action := range group.ActionNames() {
+				meta, ok := group.FindActionMeta(action)
+				if !ok || len(meta.Permissions) == 0 {
+					continue
+				}
+				chain := group.BeforeChain(action)
+				if hasRole {
+					chain = append(chain, role.BeforeChain()...)
+				}
+				guarded := false
+				for _, item := range chain {
+					if reflect.ValueOf(item.Handler).Pointer() == permissionHandlerPC {
+						guarded = true
+						break
+					}
+				}
+				if !guarded {
+					refs = append(refs, ActionRef{Role: roleLabel, Group: groupLabel, Action: action})
+				}
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].String() < refs[j].String()
+	})
+	return refs
+}
+
+// warnUnguardedPermissions 启动时提示未校验的权限声明
+func (a *Application) warnUnguardedPermissions() {
+	for _, ref := range a.UnguardedActions() {
+		logrus.WithFields(logrus.Fields{
+			"tip":    "action 声明了权限但未注册 RequirePermission, 权限不会被校验",
+			"action": ref.String(),
+		}).Warn("permission not enforced")
+	}
+}
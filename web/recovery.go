@@ -0,0 +1,112 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/http_client"
+	"catuan/components/reporters"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"runtime/debug"
+	"time"
+)
+
+// RequestIDHeader 请求 ID 请求头, 请求未携带时自动生成并通过响应头返回
+const RequestIDHeader = "X-Request-Id"
+
+// RequestID 当前请求的 ID, 用于关联日志与异常上报
+func (c *Context) RequestID() string {
+	if c.requestID == "" {
+		c.requestID = c.GetHeader(RequestIDHeader)
+		if c.requestID == "" || len(c.requestID) > 128 {
+			b := make([]byte, 16)
+			_, _ = rand.Read(b)
+			c.requestID = hex.EncodeToString(b)
+		}
+	}
+	return c.requestID
+}
+
+// UseReporter 添加 panic 上报
+func (a *Application) UseReporter(reporter ...reporters.Reporter) {
+	a.reporters = append(a.reporters, reporter...)
+}
+
+// InitReporters 根据配置创建 webhook/文件/sentry 上报
+func (a *Application) InitReporters() {
+	if a.appConf == nil || a.appConf.Report == nil {
+		return
+	}
+	info := a.appConf.Report
+	if info.Webhook != "" {
+		a.UseReporter(reporters.NewWebhookReporter(info.Webhook, nil))
+	}
+	if info.File != "" {
+		reporter, err := reporters.NewFileReporter(info.File)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip":  "创建异常上报文件失败",
+				"file": info.File,
+			}).Error(err.Error())
+		} else {
+			a.UseReporter(reporter)
+		}
+	}
+	if info.SentryDSN != "" {
+		reporter, err := reporters.NewSentryReporter(info.SentryDSN, a.activeEnv, nil)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "创建 sentry 上报失败",
+			}).Error(err.Error())
+		} else {
+			a.UseReporter(reporter)
+		}
+	}
+}
+
+// reportRedactor 上报 url 时脱敏的参数
+var reportRedactor = http_client.NewRedactor(append([]string{"token", "password"}, http_client.DefaultRedactFields...)...)
+
+// handlePanic 记录 panic 及调用栈并异步上报, 返回通用错误响应, 需在 recover 的 defer 函数中直接调用
+func (a *Application) handlePanic(c *Context, r any) *comm.RespResult {
+	event := &reporters.Event{
+		Time:      time.Now(),
+		Message:   fmt.Sprint(r),
+		Stack:     string(debug.Stack()),
+		Frames:    reporters.Callers(1),
+		RequestID: c.RequestID(),
+		Role:      c.RoleLabel(),
+		Group:     c.GroupLabel(),
+		Action:    c.ActionLabel(),
+		UserID:    c.UserID(),
+	}
+	if c.Request != nil {
+		event.Method = c.Request.Method
+		// websocket 的 access_token 等敏感参数不上报
+		event.URL = reportRedactor.URL(c.Request.URL)
+	}
+	logrus.WithFields(logrus.Fields{
+		"tip":       "处理请求时发生 panic",
+		"requestId": event.RequestID,
+		"role":      event.Role,
+		"group":     event.Group,
+		"action":    event.Action,
+		"stack":     event.Stack,
+	}).Error(event.Message)
+	a.observePanic(c)
+	if len(a.reporters) > 0 {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+			if err := reporters.Multi(a.reporters...).Report(ctx, event); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"tip":       "panic 上报失败",
+					"requestId": event.RequestID,
+				}).Error(err.Error())
+			}
+		}()
+	}
+	return comm.ErrFail.Result()
+}
@@ -2,20 +2,36 @@ package web
 
 type RoleInf interface {
 	Call(c *Context)
+	Invoke(c *Context, next NextFunc)
 	UseBefore(orderNum int, h HandlerFunc)
+	UseAfter(h HandlerFunc, destAction ...string)
+	UseAround(h AroundFunc, destAction ...string)
 	FindBefore() []HandlerFunc
+	BeforeChain() []OrderedHandler
 	RoleLabel() string
 }
 
+var _ RoleInf = (*Role)(nil)
+
 type Role struct {
 	roleLabel    string
-	beforeHandle []HandlerFunc
+	beforeHandle []OrderedHandler
+
+	// 以下 handler 的 destAction 为 group.action 格式, 空表示所有 action
+	afterHandlers  map[string][]HandlerFunc
+	aroundHandlers map[string][]AroundFunc
+	commAfters     []HandlerFunc
+	commArounds    []AroundFunc
 }
 
 func NewRole(roleLabel string) *Role {
 	return &Role{
-		roleLabel:    roleLabel,
-		beforeHandle: make([]HandlerFunc, 0),
+		roleLabel:      roleLabel,
+		beforeHandle:   make([]OrderedHandler, 0),
+		afterHandlers:  make(map[string][]HandlerFunc),
+		aroundHandlers: make(map[string][]AroundFunc),
+		commAfters:     make([]HandlerFunc, 0),
+		commArounds:    make([]AroundFunc, 0),
 	}
 }
 
@@ -23,18 +39,66 @@ func (r *Role) RoleLabel() string {
 	return r.roleLabel
 }
 
-func (r *Role) UseBefore(h ...HandlerFunc) {
-	r.beforeHandle = append(r.beforeHandle, h...)
+// UseBefore 注册 before handler, orderNum 越小越先执行, 相同时按注册顺序执行
+func (r *Role) UseBefore(orderNum int, h HandlerFunc) {
+	r.beforeHandle = mergeOrdered(r.beforeHandle, []OrderedHandler{newOrderedHandler(orderNum, h)})
+}
+
+// UseAfter 注册 after handler, 在分组处理完成后执行(包括被中断的请求), destAction 格式为 group.action
+func (r *Role) UseAfter(h HandlerFunc, destAction ...string) {
+	if len(destAction) == 0 {
+		r.commAfters = append(r.commAfters, h)
+		return
+	}
+	for _, action := range destAction {
+		r.afterHandlers[action] = append(r.afterHandlers[action], h)
+	}
+}
+
+// UseAround 注册 around handler, 包裹分组的处理流程, destAction 格式为 group.action
+func (r *Role) UseAround(h AroundFunc, destAction ...string) {
+	if len(destAction) == 0 {
+		r.commArounds = append(r.commArounds, h)
+		return
+	}
+	for _, action := range destAction {
+		r.aroundHandlers[action] = append(r.aroundHandlers[action], h)
+	}
 }
 
+// FindBefore 按执行顺序返回 before handler
 func (r *Role) FindBefore() []HandlerFunc {
-	return r.beforeHandle
+	return orderedFuncs(r.beforeHandle)
+}
+
+// BeforeChain 按执行顺序返回 before handler 的副本
+func (r *Role) BeforeChain() []OrderedHandler {
+	return append([]OrderedHandler{}, r.beforeHandle...)
 }
 
+// Call 按顺序执行 before handler, 遇到 AbortHandler 后停止
 func (r *Role) Call(c *Context) {
-	if r.beforeHandle != nil {
-		for _, h := range r.beforeHandle {
-			h(c)
-		}
+	runOrdered(c, "role.before", r.beforeHandle)
+}
+
+// Invoke 执行 before handler, 再由 around handler 包裹执行 next, 最后执行 after handler
+func (r *Role) Invoke(c *Context, next NextFunc) {
+	key := c.GroupLabel() + "." + c.ActionLabel()
+	if len(r.beforeHandle) > 0 {
+		c.tracePhase("role.before", func() {
+			r.Call(c)
+		})
+	}
+	if c.IsNext() {
+		arounds := make([]AroundFunc, 0, len(r.commArounds)+len(r.aroundHandlers[key]))
+		arounds = append(arounds, r.commArounds...)
+		arounds = append(arounds, r.aroundHandlers[key]...)
+		runAround(c, arounds, next)
+	}
+	if len(r.commAfters) > 0 || len(r.afterHandlers[key]) > 0 {
+		c.tracePhase("role.after", func() {
+			runHandlers(c, r.commAfters)
+			runHandlers(c, r.afterHandlers[key])
+		})
 	}
 }
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema 接口参数结构描述, 字段与 OpenAPI 3 Schema Object 对应
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf 根据类型生成 Schema, 结构体字段名取 json tag, binding:"required" 的字段标记为必填
+func SchemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return nil
+	}
+	return schemaOf(t, make(map[reflect.Type]bool))
+}
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	}
+	var s *Schema
+	switch t.Kind() {
+	case reflect.Bool:
+		s = &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		s = &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		s = &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		s = &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		s = &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		s = &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s = &Schema{Type: "string", Format: "byte"}
+			break
+		}
+		s = &Schema{Type: "array", Items: schemaOf(t.Elem(), visiting)}
+	case reflect.Map:
+		s = &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			// 递归类型不再展开
+			s = &Schema{Type: "object", Description: t.Name()}
+			break
+		}
+		visiting[t] = true
+		s = &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		structFields(t, s, visiting)
+		delete(visiting, t)
+	default:
+		// interface 等无法确定的类型
+		s = &Schema{}
+	}
+	s.Nullable = s.Nullable || nullable
+	return s
+}
+
+func structFields(t reflect.Type, s *Schema, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// 嵌入自身(如 *Self)时不再展开
+				if !visiting[ft] {
+					visiting[ft] = true
+					structFields(ft, s, visiting)
+					delete(visiting, ft)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = schemaOf(field.Type, visiting)
+		if isRequiredField(field) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func isRequiredField(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"catuan/components/sessions"
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrSessionNotConfigured = errors.New("会话未配置")
+
+// Session 当前请求的会话, 根据请求头中的会话 ID 加载, 请求结束后自动保存
+func (c *Context) Session() (*sessions.Session, error) {
+	if c.session == nil {
+		if c.app == nil || c.app.sessions == nil {
+			return nil, ErrSessionNotConfigured
+		}
+		m := c.app.sessions
+		ctx := context.Background()
+		if c.Request != nil {
+			ctx = c.Request.Context()
+		}
+		c.session = m.Load(ctx, c.GetHeader(m.Header()))
+	}
+	return c.session, nil
+}
+
+// saveSession 保存会话, 新会话通过响应头返回会话 ID
+func (c *Context) saveSession() {
+	if c.session == nil {
+		return
+	}
+	if err := c.session.Save(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"tip":    "保存会话异常",
+			"role":   c.RoleLabel(),
+			"group":  c.GroupLabel(),
+			"action": c.ActionLabel(),
+		}).Error(err.Error())
+		return
+	}
+	if c.session.Issued() {
+		c.Header(c.app.sessions.Header(), c.session.ID())
+	}
+}
@@ -0,0 +1,181 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	StreamSSE    = "sse"    // text/event-stream
+	StreamNDJSON = "ndjson" // application/x-ndjson, 每行一个 json
+)
+
+// ErrStreamClosed 客户端断开或超时后继续发送时返回
+var ErrStreamClosed = errors.New("stream closed")
+
+var sseNewline = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// StreamHandlerFunc 流式 action, 返回 error 时若已开始发送则以 error 事件发送, 否则按普通响应返回, 错误处理同 Context.Fail
+type StreamHandlerFunc func(c *Context, s *Stream) error
+
+// Stream 流式响应, 首次发送时写入响应头, 之后 before handler 设置的 Result 不再生效
+type Stream struct {
+	c       *Context
+	ctx     context.Context
+	mode    string
+	started bool
+}
+
+func newStream(c *Context) *Stream {
+	mode := StreamSSE
+	if strings.Contains(c.GetHeader("Accept"), "application/x-ndjson") {
+		mode = StreamNDJSON
+	}
+	return &Stream{c: c, ctx: c.Request.Context(), mode: mode}
+}
+
+// Context 客户端断开或超时后结束
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Stream) Mode() string {
+	return s.mode
+}
+
+// Started 是否已经开始发送
+func (s *Stream) Started() bool {
+	return s.started
+}
+
+// Send 发送数据, 字符串原样发送, 其他类型编码为 json
+func (s *Stream) Send(data any) error {
+	return s.SendEvent("", data)
+}
+
+// SendEvent 发送命名事件, ndjson 模式下编码为 {"event":"","data":{}}
+func (s *Stream) SendEvent(event string, data any) error {
+	if s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+	buf := &bytes.Buffer{}
+	if s.mode == StreamNDJSON {
+		var line any = data
+		if event != "" {
+			line = map[string]any{"event": event, "data": data}
+		}
+		if err := json.NewEncoder(buf).Encode(line); err != nil {
+			return err
+		}
+	} else {
+		payload, ok := data.(string)
+		if !ok {
+			raw, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			payload = string(raw)
+		}
+		// sse 中 \r 同样是换行, 统一后按行拆分, 避免破坏事件边界
+		payload = sseNewline.Replace(payload)
+		if event != "" {
+			buf.WriteString("event: " + strings.ReplaceAll(sseNewline.Replace(event), "\n", " ") + "\n")
+		}
+		for _, line := range strings.Split(payload, "\n") {
+			buf.WriteString("data: " + line + "\n")
+		}
+		buf.WriteString("\n")
+	}
+	return s.write(buf.Bytes())
+}
+
+// Ping 发送 sse 注释行保持连接, ndjson 模式下发送空行
+func (s *Stream) Ping() error {
+	if s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+	if s.mode == StreamNDJSON {
+		return s.write([]byte("\n"))
+	}
+	return s.write([]byte(": ping\n\n"))
+}
+
+func (s *Stream) write(b []byte) error {
+	if !s.started {
+		s.start()
+	}
+	if _, err := s.c.Writer.Write(b); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+func (s *Stream) start() {
+	s.started = true
+	s.c.written = true
+	// 响应头发送前保存会话, 保证新会话的 header 能够返回
+	s.c.saveSession()
+	header := s.c.Writer.Header()
+	if s.mode == StreamNDJSON {
+		header.Set("Content-Type", "application/x-ndjson")
+	} else {
+		header.Set("Content-Type", "text/event-stream")
+	}
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	s.c.Writer.WriteHeader(http.StatusOK)
+}
+
+// BindStream 绑定流式 action, 同样经过 role/group 的 before handler
+func (g *Group) BindStream(action string, h StreamHandlerFunc, opts ...ActionOption) {
+	opts = append(opts, func(meta *ActionMeta) {
+		meta.Stream = true
+	})
+	g.BindAction(action, func(c *Context) {
+		s := newStream(c)
+		err := h(c, s)
+		if err == nil {
+			return
+		}
+		if !s.started {
+			c.Fail(err)
+			return
+		}
+		if errors.Is(err, ErrStreamClosed) {
+			return
+		}
+		_ = s.SendEvent("error", c.localize(c.errorResult(err)))
+	}, opts...)
+}
+
+// routeSync 流式/websocket/下载 action 及开启同步处理时在当前协程执行, 不受默认超时限制, 通过 WithTimeout 设置超时
+// 未直接写入响应时(例如被 before handler 中断)按普通响应返回
+func (a *Application) routeSync(c *Context, meta *ActionMeta) {
+	if meta != nil && meta.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), meta.Timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			resp := a.handlePanic(c, r)
+			if !c.written {
+				c.WriteResponse(resp)
+			}
+		}
+	}()
+	a.router(c)
+	c.saveSession()
+	if c.written {
+		return
+	}
+	if c.resp != nil {
+		c.WriteResponse(c.resp)
+	}
+}
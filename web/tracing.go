@@ -0,0 +1,151 @@
+package web
+
+import (
+	"catuan/comm"
+	"catuan/components/tracing"
+	"context"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Tracer 未配置时使用不导出的 tracer, 仍会透传上游 traceparent
+func (a *Application) Tracer() *tracing.Tracer {
+	a.tracerOnce.Do(func() {
+		if a.tracer == nil {
+			a.tracer = tracing.NewTracer(tracing.Options{})
+		}
+	})
+	return a.tracer
+}
+
+// UseTracer 设置 tracer, 数据库和 redis 需自行注册 tracing.GormPlugin 和 tracing.RedisHook
+func (a *Application) UseTracer(t *tracing.Tracer) {
+	a.tracer = t
+}
+
+// InitTracing 根据配置创建 tracer 并为已连接的数据库和 redis 注册 trace
+func (a *Application) InitTracing() {
+	if a.appConf == nil || a.appConf.Tracing == nil {
+		return
+	}
+	info := a.appConf.Tracing
+	exporters := make([]tracing.Exporter, 0)
+	if info.Endpoint != "" {
+		exporter, err := tracing.NewOTLPExporter(info.Endpoint, info.Headers, nil)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip":      "创建 otlp exporter 失败",
+				"endpoint": info.Endpoint,
+			}).Error(err.Error())
+		} else {
+			exporters = append(exporters, exporter)
+		}
+	}
+	if info.Stdout {
+		exporters = append(exporters, tracing.NewStdoutExporter(nil))
+	}
+	a.UseTracer(tracing.NewTracer(tracing.Options{
+		Service:     info.Service,
+		Exporters:   exporters,
+		SampleRatio: info.SampleRatio,
+	}))
+	for i, db := range a.cdbChain {
+		if err := db.Use(tracing.GormPlugin{}); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tip": "数据库注册 trace 失败",
+				"db":  i,
+			}).Error(err.Error())
+		}
+	}
+	for _, rdb := range a.credisChain {
+		rdb.AddHook(tracing.RedisHook{})
+	}
+}
+
+// Span 当前阶段的 span
+func (c *Context) Span() *tracing.Span {
+	if c.span != nil {
+		return c.span
+	}
+	return c.traceSpan
+}
+
+// TraceID 当前请求的 trace id, 未开启 trace 时为空
+func (c *Context) TraceID() string {
+	return c.Span().TraceID()
+}
+
+// TraceContext 包含当前 span 的 ctx, 传给 db.WithContext/redis/http 请求以创建子 span 并向下游传播
+func (c *Context) TraceContext() context.Context {
+	ctx := context.Background()
+	if c.Request != nil {
+		ctx = c.Request.Context()
+	}
+	if span := c.Span(); span != nil {
+		ctx = tracing.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
+
+// tracePhase 为 handler 执行阶段创建子 span, 期间 TraceContext 以该 span 为父级
+func (c *Context) tracePhase(name string, fn func()) {
+	parent := c.Span()
+	if parent == nil {
+		fn()
+		return
+	}
+	_, span := tracing.StartSpan(tracing.ContextWithSpan(context.Background(), parent), name)
+	prev := c.span
+	c.span = span
+	finished := false
+	defer func() {
+		// panic 时仍结束 span, 不在此处 recover 以保留原始调用栈
+		if !finished {
+			span.SetStatus(tracing.StatusError, "panic")
+		}
+		c.span = prev
+		span.End()
+	}()
+	fn()
+	finished = true
+}
+
+// startTrace 根据请求头的 traceparent 创建请求 span
+func (a *Application) startTrace(c *Context) {
+	ctx := context.Background()
+	if c.Request != nil {
+		ctx = c.Request.Context()
+		if sc, ok := tracing.Extract(c.Request.Header); ok {
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+		}
+	}
+	_, span := a.Tracer().Start(ctx, strings.Join(routeLabels(c), "/"), tracing.WithKind(tracing.SpanKindServer))
+	span.SetAttribute("catuan.request_id", c.RequestID())
+	if c.Request != nil {
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.target", c.Request.URL.Path)
+	}
+	c.traceSpan = span
+}
+
+func (a *Application) endTrace(c *Context, resp *comm.RespResult, timeout bool) {
+	span := c.traceSpan
+	if span == nil {
+		return
+	}
+	status := http.StatusOK
+	if resp != nil {
+		status = respStatus(resp)
+		span.SetAttribute("catuan.err_code", resp.ErrCode)
+	}
+	span.SetAttribute("http.status_code", status)
+	switch {
+	case timeout:
+		span.SetStatus(tracing.StatusError, "timeout")
+	case status >= http.StatusInternalServerError:
+		span.SetStatus(tracing.StatusError, strconv.Itoa(resp.ErrCode)+" "+resp.ErrMsg)
+	}
+	span.End()
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"catuan/components/websockets"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// WebSocketHandlerFunc 连接建立后调用, 可在其中订阅主题及设置 conn.OnMessage
+type WebSocketHandlerFunc func(c *Context, conn *websockets.Conn)
+
+// BindWebSocket 绑定 websocket action, 握手前执行 role/group 的 before handler, 中断时按普通响应返回
+func (g *Group) BindWebSocket(action string, h WebSocketHandlerFunc, opts ...ActionOption) {
+	opts = append(opts, func(meta *ActionMeta) {
+		meta.WebSocket = true
+	})
+	g.BindAction(action, func(c *Context) {
+		c.written = true
+		c.saveSession()
+		c.app.WebSocketHub().Upgrade(c.Writer, c.Request, c.UserID(), func(conn *websockets.Conn) {
+			h(c, conn)
+		})
+	}, opts...)
+}
+
+// InitWebSocket 根据配置创建 websocket hub, 开启 fanout 时通过 redis pub/sub 在实例间转发消息
+func (a *Application) InitWebSocket() {
+	if a.appConf == nil || a.appConf.WebSocket == nil {
+		return
+	}
+	info := a.appConf.WebSocket
+	opt := websockets.Options{
+		PingInterval: time.Duration(info.PingInterval) * time.Second,
+		ReadTimeout:  time.Duration(info.ReadTimeout) * time.Second,
+		SendBuffer:   info.SendBuffer,
+	}
+	if info.Fanout {
+		if client := a.GetRedis(info.Redis); client != nil {
+			opt.Broker = websockets.NewRedisBroker(client, info.Channel)
+		} else {
+			logrus.WithFields(logrus.Fields{
+				"tip":   "websocket 转发使用的redis不存在, 仅在本实例内发送",
+				"redis": info.Redis,
+			}).Error("websocket fanout init failed")
+		}
+	}
+	a.wsHub = websockets.NewHub(opt)
+}
+
+// UseWebSocketHub 使用自定义的 hub
+func (a *Application) UseWebSocketHub(hub *websockets.Hub) {
+	a.wsHub = hub
+}
+
+// WebSocketHub 未配置时创建仅在本实例内发送的 hub
+func (a *Application) WebSocketHub() *websockets.Hub {
+	a.wsHubOnce.Do(func() {
+		if a.wsHub == nil {
+			a.wsHub = websockets.NewHub(websockets.Options{})
+		}
+	})
+	return a.wsHub
+}
@@ -0,0 +1,235 @@
+// Package webtest 在进程内调用 action, 不启动 http 服务
+package webtest
+
+import (
+	"bytes"
+	"catuan/comm"
+	"catuan/components/auth"
+	"catuan/web"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Harness 包装 Application, 可直接调用 UseRole/UseGroup 等方法注册路由
+type Harness struct {
+	*web.Application
+
+	mu     sync.Mutex
+	claims *auth.Claims
+	clock  *Clock
+}
+
+// New 创建用于测试的 Application
+func New() *Harness {
+	gin.SetMode(gin.TestMode)
+	return Wrap(web.New("test"))
+}
+
+// Wrap 包装已有的 Application, 并开启同步处理
+func Wrap(app *web.Application) *Harness {
+	gin.SetMode(gin.TestMode)
+	app.SyncDispatch(true)
+	return &Harness{Application: app}
+}
+
+// AuthAs 之后的请求以 claims 身份调用, 配置了 JWT 时同时签发 token 写入 Authorization
+func (h *Harness) AuthAs(claims *auth.Claims) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.claims = claims
+}
+
+// Logout 取消 AuthAs
+func (h *Harness) Logout() {
+	h.AuthAs(nil)
+}
+
+// FreezeTime 将 comm.Now 固定为 t, 全局生效, 使用后需调用 Close 恢复
+func (h *Harness) FreezeTime(t time.Time) *Clock {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clock = NewClock(t)
+	comm.SetClock(h.clock.Now)
+	return h.clock
+}
+
+// Close 恢复系统时间
+func (h *Harness) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clock != nil {
+		comm.SetClock(nil)
+		h.clock = nil
+	}
+}
+
+// Invoke 以 POST 调用 action 并解析响应, action 可带 query 参数, 例如 detail?id=1
+// body 为 nil 时不带请求体, []byte/string 原样发送, url.Values 以表单发送, 其他类型以 json 发送
+func (h *Harness) Invoke(role, group, action string, body any, headers map[string]string) *Result {
+	req := httptest.NewRequest(http.MethodPost, "/"+role+"/"+group+"/"+action, nil)
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		setBody(req, b)
+	case string:
+		setBody(req, []byte(b))
+	case url.Values:
+		setBody(req, []byte(b.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			panic("webtest: marshal body: " + err.Error())
+		}
+		setBody(req, data)
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	h.mu.Lock()
+	claims := h.claims
+	h.mu.Unlock()
+	if claims != nil && h.JWT() != nil {
+		token, err := h.JWT().Issue(*claims)
+		if err != nil {
+			panic("webtest: issue token: " + err.Error())
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	gc, _ := gin.CreateTestContext(w)
+	gc.Request = req
+	c := web.NewContext(gc)
+	action, _, _ = strings.Cut(action, "?")
+	c.InitRoleInfo(role, group, action)
+	if claims != nil {
+		c.SetClaims(claims)
+	}
+	res := &Result{Before: make([]string, 0)}
+	c.ObserveHandlers(func(stage, name string) {
+		res.Before = append(res.Before, name)
+	})
+	h.Router(c)
+
+	res.Status = w.Code
+	res.Header = w.Header()
+	res.Body = w.Body.Bytes()
+	decoded := struct {
+		ErrCode int             `json:"err_code"`
+		ErrMsg  string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(res.Body, &decoded); err == nil {
+		res.ErrCode = decoded.ErrCode
+		res.ErrMsg = decoded.ErrMsg
+		res.data = decoded.Data
+		if len(decoded.Data) > 0 {
+			_ = json.Unmarshal(decoded.Data, &res.Data)
+		}
+	}
+	return res
+}
+
+func setBody(req *http.Request, data []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+}
+
+// Result 调用结果, 响应不是 json 时只有 Status/Header/Body
+type Result struct {
+	comm.RespResult
+	Header http.Header
+	Body   []byte
+	Before []string // 已执行的 before handler 函数名, 按执行顺序
+
+	data json.RawMessage
+}
+
+// Decode 将响应 data 解析到 v
+func (r *Result) Decode(v any) error {
+	if len(r.data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.data, v)
+}
+
+// BeforeRan 指定的 before handler 是否执行, name 可省略包路径, 例如 web.JWTAuth
+func (r *Result) BeforeRan(name string) bool {
+	for _, ran := range r.Before {
+		if matchName(ran, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertBeforeRan 断言 before handler 均已执行
+func (r *Result) AssertBeforeRan(t testing.TB, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if !r.BeforeRan(name) {
+			t.Errorf("before handler %s did not run, ran: %v", name, r.Before)
+		}
+	}
+}
+
+// AssertBeforeNotRan 断言 before handler 均未执行
+func (r *Result) AssertBeforeNotRan(t testing.TB, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if r.BeforeRan(name) {
+			t.Errorf("before handler %s should not run, ran: %v", name, r.Before)
+		}
+	}
+}
+
+// matchName 比较时忽略包路径和匿名函数后缀, catuan/web.JWTAuth.func1 可匹配 web.JWTAuth
+func matchName(full, name string) bool {
+	if full == name {
+		return true
+	}
+	short := full
+	if i := strings.LastIndex(full, "/"); i >= 0 {
+		short = full[i+1:]
+	}
+	return short == name || strings.HasPrefix(short, name+".") || strings.HasPrefix(full, name+".")
+}
+
+// Clock 可手动调整的时间
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}